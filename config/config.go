@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -11,6 +14,40 @@ import (
 type Config struct {
 	ServerPort string
 	DbURI      string
+
+	// Scheduled messages
+	SchedulerInterval time.Duration // how often the scheduler looks for due messages
+	SchedulerLease    time.Duration // how long an instance may hold a claimed message
+
+	// Messages
+	MessageEditWindow time.Duration // how long after sending the author may edit
+	MaxPinnedMessages int           // pins allowed per chat at any one time
+
+	// Media
+	UploadSessionTTL   time.Duration // how long an idle resumable upload is kept
+	UploadCleanupEvery time.Duration // how often abandoned uploads are swept
+	MediaURLSecret     string        // HMAC key for signed media URLs; share it across instances
+	MediaURLTTL        time.Duration // default lifetime of a signed media URL
+	MaxImageSize       int64         // largest image upload, in bytes
+	MaxVideoSize       int64         // largest video upload, in bytes
+	MaxAudioSize       int64         // largest audio upload, in bytes
+	MaxDocumentSize    int64         // largest upload of any other type, in bytes
+	AllowedMimeTypes   []string      // if set, only these MIME types ("image/*" style wildcards allowed) may be uploaded
+	DeniedMimeTypes    []string      // MIME types that may never be uploaded; wins over the allow list
+	UserStorageQuota   int64         // bytes of media each user may store; 0 means unlimited
+	MediaGCInterval    time.Duration // how often unreferenced media is collected; 0 disables it
+	MediaGCGrace       time.Duration // how old an unreferenced upload must be before it is collected
+	MediaRetention     time.Duration // how long media stays referenced by delivered messages; 0 keeps it forever
+	MediaGCDryRun      bool          // report what the collector would delete without deleting it (the default)
+	MediaGCLooseFiles  bool          // also collect GridFS files from before the media collection
+
+	// Video transcoding
+	TranscodeWorkers      int           // concurrent ffmpeg transcodes per instance; 0 disables transcoding
+	TranscodeMaxSide      int           // the shorter side of a transcoded video is scaled down to this, in pixels
+	TranscodeKeepOriginal bool          // keep the uploaded file next to the transcoded one
+	TranscodePollInterval time.Duration // how often idle workers look for queued jobs
+	TranscodeLease        time.Duration // how long a job outlives its worker before it is retried
+
 	// Add other configurations like Firebase, JWT secret, etc.
 }
 
@@ -27,6 +64,35 @@ func LoadConfig() {
 	// Load configurations
 	Cfg.ServerPort = getEnv("PORT", ":8080")
 	Cfg.DbURI = getEnv("DB_URL", "mongodb://localhost:27017/whatsapp_clone")
+	Cfg.SchedulerInterval = getDurationEnv("SCHEDULER_INTERVAL", 5*time.Second)
+	Cfg.SchedulerLease = getDurationEnv("SCHEDULER_LEASE", 30*time.Second)
+	Cfg.MessageEditWindow = getDurationEnv("MESSAGE_EDIT_WINDOW", 15*time.Minute)
+	Cfg.MaxPinnedMessages = getIntEnv("MAX_PINNED_MESSAGES", 3)
+	Cfg.UploadSessionTTL = getDurationEnv("UPLOAD_SESSION_TTL", 24*time.Hour)
+	Cfg.UploadCleanupEvery = getDurationEnv("UPLOAD_CLEANUP_INTERVAL", time.Hour)
+	Cfg.MediaURLSecret = getEnv("MEDIA_URL_SECRET", "")
+	Cfg.MediaURLTTL = getDurationEnv("MEDIA_URL_TTL", 15*time.Minute)
+	Cfg.MaxImageSize = getSizeEnv("MAX_IMAGE_SIZE", 16<<20)
+	Cfg.MaxVideoSize = getSizeEnv("MAX_VIDEO_SIZE", 100<<20)
+	Cfg.MaxAudioSize = getSizeEnv("MAX_AUDIO_SIZE", 16<<20)
+	Cfg.MaxDocumentSize = getSizeEnv("MAX_DOCUMENT_SIZE", 100<<20)
+	Cfg.AllowedMimeTypes = getListEnv("ALLOWED_MIME_TYPES", nil)
+	Cfg.DeniedMimeTypes = getListEnv("DENIED_MIME_TYPES", []string{
+		"application/x-msdownload",
+		"application/x-sh",
+		"application/x-elf",
+	})
+	Cfg.UserStorageQuota = getSizeEnv("USER_STORAGE_QUOTA", 2<<30)
+	Cfg.MediaGCInterval = getDurationEnv("MEDIA_GC_INTERVAL", 6*time.Hour)
+	Cfg.MediaGCGrace = getDurationEnv("MEDIA_GC_GRACE", 24*time.Hour)
+	Cfg.MediaRetention = getDurationEnv("MEDIA_RETENTION", 0)
+	Cfg.MediaGCDryRun = getBoolEnv("MEDIA_GC_DRY_RUN", true)
+	Cfg.MediaGCLooseFiles = getBoolEnv("MEDIA_GC_LOOSE_FILES", false)
+	Cfg.TranscodeWorkers = getIntEnv("TRANSCODE_WORKERS", 2)
+	Cfg.TranscodeMaxSide = getIntEnv("TRANSCODE_MAX_SIDE", 720)
+	Cfg.TranscodeKeepOriginal = getBoolEnv("TRANSCODE_KEEP_ORIGINAL", true)
+	Cfg.TranscodePollInterval = getDurationEnv("TRANSCODE_POLL_INTERVAL", 5*time.Second)
+	Cfg.TranscodeLease = getDurationEnv("TRANSCODE_LEASE", 10*time.Minute)
 	// Load other configuration variables as needed
 }
 
@@ -38,3 +104,94 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+// getIntEnv parses an integer from the environment, falling back to the
+// default when it is unset or malformed
+func getIntEnv(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid integer for %s (%q), using %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+// getDurationEnv parses a duration such as "30s" or "15m" from the environment,
+// falling back to the default when it is unset or malformed
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration for %s (%q), using %v", key, value, defaultValue)
+		return defaultValue
+	}
+	return d
+}
+
+// getBoolEnv parses a boolean from the environment, falling back to the
+// default when it is unset or malformed
+func getBoolEnv(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid boolean for %s (%q), using %v", key, value, defaultValue)
+		return defaultValue
+	}
+	return b
+}
+
+// getSizeEnv parses a byte size such as "16MB", "2GB" or a plain number of
+// bytes from the environment, falling back to the default when it is unset or
+// malformed
+func getSizeEnv(key string, defaultValue int64) int64 {
+	value := strings.ToUpper(strings.TrimSpace(os.Getenv(key)))
+	if value == "" {
+		return defaultValue
+	}
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		factor int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.factor
+			break
+		}
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		log.Printf("Invalid size for %s (%q), using %d bytes", key, os.Getenv(key), defaultValue)
+		return defaultValue
+	}
+	return n * multiplier
+}
+
+// getListEnv reads a comma separated list from the environment. An unset
+// variable gives the default; one set to "none" gives an empty list.
+func getListEnv(key string, defaultValue []string) []string {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return defaultValue
+	}
+	if value == "none" {
+		return nil
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, strings.ToLower(item))
+		}
+	}
+	return items
+}
@@ -0,0 +1,32 @@
+package config
+
+import "testing"
+
+func TestGetSizeEnv(t *testing.T) {
+	const fallback = 42
+
+	tests := []struct {
+		value string
+		want  int64
+	}{
+		{"", fallback},
+		{"1024", 1024},
+		{"512B", 512},
+		{"4KB", 4 << 10},
+		{"16MB", 16 << 20},
+		{"2GB", 2 << 30},
+		{" 16 mb ", 16 << 20},
+		{"0", 0},
+		{"-1MB", fallback},
+		{"lots", fallback},
+		{"1.5GB", fallback},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("TEST_SIZE", tt.value)
+			if got := getSizeEnv("TEST_SIZE", fallback); got != tt.want {
+				t.Errorf("getSizeEnv(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
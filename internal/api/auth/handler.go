@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"fmt"
+	"gochat_server/internal/api/media"
 	"gochat_server/internal/db"
 	"gochat_server/internal/utils"
 	"net/http"
@@ -61,8 +62,8 @@ func LoginHandler(c *gin.Context) {
 		return
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateJWT(foundUser.Phone)
+	// Generate JWT token for the user's ID, which is what the API knows them by
+	token, err := utils.GenerateJWT(user.Id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
 		return
@@ -113,14 +114,15 @@ func RegisterHandler(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	_, err = collection.InsertOne(ctx, user)
+	result, err := collection.InsertOne(ctx, user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register user"})
 		return
 	}
+	userID, _ := result.InsertedID.(primitive.ObjectID)
 
-	// Generate JWT token
-	token, err := utils.GenerateJWT(newUser.Phone)
+	// Generate JWT token for the new user's ID
+	token, err := utils.GenerateJWT(userID.Hex())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating JWT token"})
 		return
@@ -190,3 +192,51 @@ func GetUserDataHandler(c *gin.Context) {
 
 	c.JSON(http.StatusOK, user)
 }
+
+// Set the caller's profile picture. It must be an image they uploaded, and is
+// made public so anyone who sees the user can load it.
+func SetProfilePicture(c *gin.Context) {
+	userID := c.Query("user_id")
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	var request ProfilePictureRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	url := ""
+	if request.MediaID != "" {
+		if !media.IsOwnImage(userID, request.MediaID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "media_id must be the ID of an image you uploaded"})
+			return
+		}
+		if err := media.MakePublic(request.MediaID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish profile picture"})
+			return
+		}
+		url = "/api/media/" + request.MediaID
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := db.GetCollection("users").UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": bson.M{
+		"profile_picture_url": url,
+		"updated_at":          time.Now().Format(time.RFC3339),
+	}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile picture"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no user found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Profile picture updated", "profile_picture_url": url})
+}
@@ -22,3 +22,9 @@ type CappedUser struct {
 	Password    string `json:"password" binding:"required"`
 	CountryCode string `json:"country_code" binding:"required" bson:"country_code"`
 }
+
+// ProfilePictureRequest sets the caller's profile picture to an uploaded
+// image, or clears it when MediaID is empty
+type ProfilePictureRequest struct {
+	MediaID string `json:"media_id"`
+}
@@ -0,0 +1,192 @@
+package broadcast
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"gochat_server/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Create a broadcast list
+func CreateList(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing user_id"})
+		return
+	}
+
+	var request ListRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	recipients, errMsg := normalizeRecipients(userID, request.Recipients)
+	if errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	list := List{
+		ID:         primitive.NewObjectID().Hex(),
+		OwnerID:    userID,
+		Name:       request.Name,
+		Recipients: recipients,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if _, err := db.GetCollection("broadcast_lists").InsertOne(context.TODO(), list); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create broadcast list"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Broadcast list created successfully", "list": list})
+}
+
+// Get the caller's broadcast lists
+func GetLists(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing user_id"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := db.GetCollection("broadcast_lists").Find(ctx, bson.M{"owner_id": userID}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch broadcast lists"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	lists := []List{}
+	if err := cursor.All(ctx, &lists); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding broadcast lists"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"lists": lists})
+}
+
+// Get one of the caller's broadcast lists
+func GetList(c *gin.Context) {
+	list, err := FindList(c.Param("id"), c.Query("user_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Broadcast list not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"list": list})
+}
+
+// Rename a broadcast list or replace its recipients
+func UpdateList(c *gin.Context) {
+	userID := c.Query("user_id")
+
+	var request ListRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	recipients, errMsg := normalizeRecipients(userID, request.Recipients)
+	if errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	var list List
+	err := db.GetCollection("broadcast_lists").FindOneAndUpdate(
+		context.TODO(),
+		bson.M{"_id": c.Param("id"), "owner_id": userID},
+		bson.M{"$set": bson.M{
+			"name":       request.Name,
+			"recipients": recipients,
+			"updated_at": time.Now().Format(time.RFC3339),
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&list)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Broadcast list not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Broadcast list updated successfully", "list": list})
+}
+
+// Delete a broadcast list. Messages already sent to it are kept.
+func DeleteList(c *gin.Context) {
+	result, err := db.GetCollection("broadcast_lists").DeleteOne(context.TODO(), bson.M{
+		"_id":      c.Param("id"),
+		"owner_id": c.Query("user_id"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete broadcast list"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Broadcast list not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Broadcast list deleted successfully"})
+}
+
+// Get the messages sent to a broadcast list, newest first, each with its
+// aggregate delivery status
+func GetListMessages(c *gin.Context) {
+	userID := c.Query("user_id")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := db.GetCollection("broadcast_messages").Find(ctx, bson.M{"list_id": c.Param("id"), "sender_id": userID}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch broadcast messages"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var messages []Message
+	if err := cursor.All(ctx, &messages); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding broadcast messages"})
+		return
+	}
+
+	results := []gin.H{}
+	for _, message := range messages {
+		results = append(results, gin.H{
+			"_id":        message.ID,
+			"content":    message.Content,
+			"type":       message.Type,
+			"created_at": message.CreatedAt,
+			"status":     summarize(message),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"messages": results})
+}
+
+// Get the delivery status of a broadcast message, per recipient and in total
+func GetMessageStatus(c *gin.Context) {
+	var message Message
+	err := db.GetCollection("broadcast_messages").FindOne(context.TODO(), bson.M{
+		"_id":       c.Param("id"),
+		"sender_id": c.Query("user_id"),
+	}).Decode(&message)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Broadcast message not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message, "status": summarize(message)})
+}
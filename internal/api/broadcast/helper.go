@@ -0,0 +1,105 @@
+package broadcast
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gochat_server/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxRecipients caps the size of a broadcast list
+const maxRecipients = 256
+
+const (
+	StatusSent      = "sent"
+	StatusDelivered = "delivered"
+	StatusRead      = "read"
+)
+
+// FindList loads a broadcast list owned by ownerID
+func FindList(listID, ownerID string) (List, error) {
+	var list List
+	err := db.GetCollection("broadcast_lists").FindOne(context.TODO(), bson.M{"_id": listID, "owner_id": ownerID}).Decode(&list)
+	return list, err
+}
+
+// normalizeRecipients drops blanks, duplicates and the owner, and enforces the
+// list size limit
+func normalizeRecipients(ownerID string, userIDs []string) ([]string, string) {
+	seen := make(map[string]bool)
+	recipients := []string{}
+	for _, userID := range userIDs {
+		if userID == "" || userID == ownerID || seen[userID] {
+			continue
+		}
+		seen[userID] = true
+		recipients = append(recipients, userID)
+	}
+
+	if len(recipients) == 0 {
+		return nil, "A broadcast list needs at least one recipient"
+	}
+	if len(recipients) > maxRecipients {
+		return nil, fmt.Sprintf("A broadcast list can have at most %d recipients", maxRecipients)
+	}
+	return recipients, ""
+}
+
+// RecordMessage stores a broadcast and the copies sent for it
+func RecordMessage(message Message) error {
+	_, err := db.GetCollection("broadcast_messages").InsertOne(context.TODO(), message)
+	return err
+}
+
+// MarkDelivered moves the broadcast copy with messageID from sent to delivered
+func MarkDelivered(messageID string) error {
+	_, err := db.GetCollection("broadcast_messages").UpdateOne(
+		context.TODO(),
+		bson.M{"recipients": bson.M{"$elemMatch": bson.M{"message_id": messageID, "status": StatusSent}}},
+		bson.M{"$set": bson.M{
+			"recipients.$.status":     StatusDelivered,
+			"recipients.$.updated_at": time.Now().UTC().Format(time.RFC3339),
+		}},
+	)
+	return err
+}
+
+// MarkRead marks every copy senderID broadcast to readerID as read, since a
+// read acknowledgment covers the whole 1:1 chat
+func MarkRead(senderID, readerID string) error {
+	_, err := db.GetCollection("broadcast_messages").UpdateMany(
+		context.TODO(),
+		bson.M{
+			"sender_id":  senderID,
+			"recipients": bson.M{"$elemMatch": bson.M{"user_id": readerID, "status": bson.M{"$ne": StatusRead}}},
+		},
+		bson.M{"$set": bson.M{
+			"recipients.$[r].status":     StatusRead,
+			"recipients.$[r].updated_at": time.Now().UTC().Format(time.RFC3339),
+		}},
+		options.Update().SetArrayFilters(options.ArrayFilters{
+			Filters: []interface{}{bson.M{"r.user_id": readerID}},
+		}),
+	)
+	return err
+}
+
+// summarize counts a broadcast's copies by status
+func summarize(message Message) Status {
+	status := Status{Total: len(message.Recipients)}
+	for _, recipient := range message.Recipients {
+		switch recipient.Status {
+		case StatusSent:
+			status.Sent++
+		case StatusDelivered:
+			status.Delivered++
+		case StatusRead:
+			status.Read++
+		}
+	}
+	return status
+}
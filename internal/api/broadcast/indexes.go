@@ -0,0 +1,31 @@
+package broadcast
+
+import (
+	"context"
+	"fmt"
+
+	"gochat_server/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// EnsureIndexes creates the indexes the broadcast collections rely on
+func EnsureIndexes() {
+	indexes := map[string][]mongo.IndexModel{
+		"broadcast_lists": {
+			{Keys: bson.D{{Key: "owner_id", Value: 1}, {Key: "name", Value: 1}}},
+		},
+		"broadcast_messages": {
+			{Keys: bson.D{{Key: "recipients.message_id", Value: 1}}},
+			{Keys: bson.D{{Key: "sender_id", Value: 1}, {Key: "recipients.user_id", Value: 1}}},
+			{Keys: bson.D{{Key: "list_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		},
+	}
+
+	for collection, models := range indexes {
+		if _, err := db.GetCollection(collection).Indexes().CreateMany(context.TODO(), models); err != nil {
+			fmt.Printf("Failed to create indexes on %s: %v\n", collection, err)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package broadcast
+
+// List is a named set of recipients a user can send one message to. Each
+// recipient gets it as a separate 1:1 message and never sees the others.
+type List struct {
+	ID         string   `bson:"_id" json:"_id"`
+	OwnerID    string   `bson:"owner_id" json:"owner_id"`
+	Name       string   `bson:"name" json:"name"`
+	Recipients []string `bson:"recipients" json:"recipients"`
+	CreatedAt  string   `bson:"created_at" json:"created_at"`
+	UpdatedAt  string   `bson:"updated_at" json:"updated_at"`
+}
+
+type ListRequest struct {
+	Name       string   `json:"name" binding:"required"`
+	Recipients []string `json:"recipients" binding:"required"`
+}
+
+// Recipient tracks the 1:1 copy of a broadcast sent to one user
+type Recipient struct {
+	UserID    string `bson:"user_id" json:"user_id"`
+	MessageID string `bson:"message_id" json:"message_id"`
+	Status    string `bson:"status" json:"status"` // sent, delivered or read
+	UpdatedAt string `bson:"updated_at" json:"updated_at"`
+}
+
+// Message records one broadcast and the delivery status of each copy. Its ID
+// is the ID of the message the sender sent to the list.
+type Message struct {
+	ID         string      `bson:"_id" json:"_id"`
+	ListID     string      `bson:"list_id" json:"list_id"`
+	SenderID   string      `bson:"sender_id" json:"sender_id"`
+	Content    string      `bson:"content" json:"content"`
+	Type       string      `bson:"type" json:"type"`
+	CreatedAt  string      `bson:"created_at" json:"created_at"`
+	Recipients []Recipient `bson:"recipients" json:"recipients"`
+}
+
+// Status is the per-status count of a broadcast's copies
+type Status struct {
+	Total     int `json:"total"`
+	Sent      int `json:"sent"`
+	Delivered int `json:"delivered"`
+	Read      int `json:"read"`
+}
@@ -0,0 +1,120 @@
+package channel
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gochat_server/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Get a page of the channel's posts, newest first. Pass the returned
+// next_cursor as ?cursor= to get the following page.
+func GetFeed(c *gin.Context) {
+	channel, err := FindChannel(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	filter := bson.M{"channel_id": channel.ID}
+	if cursor := c.Query("cursor"); cursor != "" {
+		filter["_id"] = bson.M{"$lt": cursor}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+	cursor, err := db.GetCollection("channel_posts").Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch channel feed"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	posts := []Post{}
+	if err := cursor.All(ctx, &posts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding channel feed"})
+		return
+	}
+
+	if !channel.ViewCounts {
+		for i := range posts {
+			posts[i].ViewCount = nil
+		}
+	}
+
+	var nextCursor string
+	if len(posts) == limit {
+		nextCursor = posts[len(posts)-1].ID
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"posts":          posts,
+		"next_cursor":    nextCursor,
+		"follower_count": channel.FollowerCount,
+	})
+}
+
+// Record that the caller viewed a post. Each user counts once, and only on
+// channels with view counts turned on.
+func ViewPost(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing user_id"})
+		return
+	}
+
+	channel, err := FindChannel(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
+		return
+	}
+	if !channel.ViewCounts {
+		c.JSON(http.StatusForbidden, gin.H{"error": "View counts are turned off for this channel"})
+		return
+	}
+
+	postID := c.Param("post_id")
+	count, err := db.GetCollection("channel_posts").CountDocuments(context.TODO(), bson.M{"_id": postID, "channel_id": channel.ID})
+	if err != nil || count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+
+	_, err = db.GetCollection("channel_post_views").InsertOne(context.TODO(), bson.M{
+		"post_id":   postID,
+		"user_id":   userID,
+		"viewed_at": time.Now().Format(time.RFC3339),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		c.JSON(http.StatusOK, gin.H{"message": "View already recorded"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record view"})
+		return
+	}
+
+	_, err = db.GetCollection("channel_posts").UpdateOne(context.TODO(), bson.M{"_id": postID}, bson.M{"$inc": bson.M{"view_count": 1}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record view"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "View recorded"})
+}
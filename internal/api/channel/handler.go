@@ -0,0 +1,275 @@
+package channel
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"gochat_server/internal/api/media"
+	"gochat_server/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Create a channel; the creator is its first admin
+func CreateChannel(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing user_id"})
+		return
+	}
+
+	var request CreateChannelRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if request.Icon != "" && !media.IsOwnImage(userID, request.Icon) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "icon must be the ID of an image you uploaded"})
+		return
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	channel := Channel{
+		ID:          primitive.NewObjectID().Hex(),
+		Name:        request.Name,
+		Description: request.Description,
+		Icon:        request.Icon,
+		CreatedBy:   userID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Admins:      []string{userID},
+		ViewCounts:  request.ViewCounts,
+	}
+	if _, err := db.GetCollection("channels").InsertOne(context.TODO(), channel); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create channel"})
+		return
+	}
+	if channel.Icon != "" {
+		media.MakePublic(channel.Icon)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Channel created successfully", "channel": channel})
+}
+
+// Get a channel's info and follower count, and whether the caller follows it
+func GetChannel(c *gin.Context) {
+	channel, err := FindChannel(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
+		return
+	}
+
+	userID := c.Query("user_id")
+	c.JSON(http.StatusOK, gin.H{
+		"channel":   channel,
+		"following": isFollowing(channel.ID, userID),
+		"is_admin":  channel.IsAdmin(userID),
+	})
+}
+
+// Update a channel's info (admins only)
+func UpdateChannel(c *gin.Context) {
+	channel, ok := loadChannelAsAdmin(c)
+	if !ok {
+		return
+	}
+
+	var request UpdateChannelRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	update := bson.M{"updated_at": time.Now().Format(time.RFC3339)}
+	if request.Name != nil {
+		if *request.Name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Channel name can't be empty"})
+			return
+		}
+		update["name"] = *request.Name
+	}
+	if request.Description != nil {
+		update["description"] = *request.Description
+	}
+	if request.Icon != nil {
+		if *request.Icon != "" {
+			if !media.IsOwnImage(c.Query("user_id"), *request.Icon) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "icon must be the ID of an image you uploaded"})
+				return
+			}
+			media.MakePublic(*request.Icon)
+		}
+		update["icon"] = *request.Icon
+	}
+	if request.ViewCounts != nil {
+		update["view_counts"] = *request.ViewCounts
+	}
+
+	if _, err := db.GetCollection("channels").UpdateOne(context.TODO(), bson.M{"_id": channel.ID}, bson.M{"$set": update}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update channel"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Channel updated successfully"})
+}
+
+// Get the channels the caller follows
+func GetFollowedChannels(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing user_id"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := db.GetCollection("channel_followers").Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch channels"})
+		return
+	}
+	var follows []Follower
+	if err := cursor.All(ctx, &follows); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding channels"})
+		return
+	}
+
+	channelIDs := []string{}
+	for _, f := range follows {
+		channelIDs = append(channelIDs, f.ChannelID)
+	}
+
+	cursor, err = db.GetCollection("channels").Find(ctx, bson.M{"_id": bson.M{"$in": channelIDs}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch channels"})
+		return
+	}
+	channels := []Channel{}
+	if err := cursor.All(ctx, &channels); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding channels"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"channels": channels})
+}
+
+// Follow a channel
+func FollowChannel(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing user_id"})
+		return
+	}
+	channel, err := FindChannel(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
+		return
+	}
+
+	followed, err := follow(channel.ID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to follow channel"})
+		return
+	}
+	if !followed {
+		c.JSON(http.StatusConflict, gin.H{"error": "Already following this channel"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Channel followed"})
+}
+
+// Stop following a channel
+func UnfollowChannel(c *gin.Context) {
+	unfollowed, err := unfollow(c.Param("id"), c.Query("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unfollow channel"})
+		return
+	}
+	if !unfollowed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not following this channel"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Channel unfollowed"})
+}
+
+// Make a user a channel admin (admins only)
+func AddChannelAdmin(c *gin.Context) {
+	channel, ok := loadChannelAsAdmin(c)
+	if !ok {
+		return
+	}
+	memberID := c.Param("member_id")
+	if channel.IsAdmin(memberID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is already an admin"})
+		return
+	}
+
+	_, err := db.GetCollection("channels").UpdateOne(
+		context.TODO(),
+		bson.M{"_id": channel.ID},
+		bson.M{
+			"$addToSet": bson.M{"admins": memberID},
+			"$set":      bson.M{"updated_at": time.Now().Format(time.RFC3339)},
+		},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add admin"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Admin added"})
+}
+
+// Remove a channel admin (admins only). The creator stays an admin.
+func RemoveChannelAdmin(c *gin.Context) {
+	channel, ok := loadChannelAsAdmin(c)
+	if !ok {
+		return
+	}
+	memberID := c.Param("member_id")
+	if !channel.IsAdmin(memberID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User is not an admin"})
+		return
+	}
+	if memberID == channel.CreatedBy {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The channel owner can't be removed as admin"})
+		return
+	}
+
+	_, err := db.GetCollection("channels").UpdateOne(
+		context.TODO(),
+		bson.M{"_id": channel.ID},
+		bson.M{
+			"$pull": bson.M{"admins": memberID},
+			"$set":  bson.M{"updated_at": time.Now().Format(time.RFC3339)},
+		},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove admin"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Admin removed"})
+}
+
+// loadChannelAsAdmin loads the :id channel and checks the caller is one of its
+// admins, writing the error response when not
+func loadChannelAsAdmin(c *gin.Context) (Channel, bool) {
+	channel, err := FindChannel(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
+		return Channel{}, false
+	}
+	if !channel.IsAdmin(c.Query("user_id")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only channel admins can do this"})
+		return Channel{}, false
+	}
+	return channel, true
+}
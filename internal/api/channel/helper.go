@@ -0,0 +1,118 @@
+package channel
+
+import (
+	"context"
+	"time"
+
+	"gochat_server/internal/api/media"
+	"gochat_server/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// FindChannel loads a channel by its ID
+func FindChannel(channelID string) (Channel, error) {
+	var channel Channel
+	err := db.GetCollection("channels").FindOne(context.TODO(), bson.M{"_id": channelID}).Decode(&channel)
+	return channel, err
+}
+
+// IsAdmin reports whether userID may post to and manage the channel
+func (ch Channel) IsAdmin(userID string) bool {
+	for _, admin := range ch.Admins {
+		if admin == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func isFollowing(channelID, userID string) bool {
+	count, err := db.GetCollection("channel_followers").CountDocuments(context.TODO(), bson.M{"channel_id": channelID, "user_id": userID})
+	return err == nil && count > 0
+}
+
+// follow adds userID to the channel's followers, keeping the follower count
+// in step. It reports false when the user already follows the channel.
+func follow(channelID, userID string) (bool, error) {
+	_, err := db.GetCollection("channel_followers").InsertOne(context.TODO(), Follower{
+		ChannelID:  channelID,
+		UserID:     userID,
+		FollowedAt: time.Now().Format(time.RFC3339),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, adjustFollowerCount(channelID, 1)
+}
+
+// unfollow removes userID from the channel's followers. It reports false when
+// the user wasn't following it.
+func unfollow(channelID, userID string) (bool, error) {
+	result, err := db.GetCollection("channel_followers").DeleteOne(context.TODO(), bson.M{"channel_id": channelID, "user_id": userID})
+	if err != nil || result.DeletedCount == 0 {
+		return false, err
+	}
+	return true, adjustFollowerCount(channelID, -1)
+}
+
+func adjustFollowerCount(channelID string, delta int) error {
+	_, err := db.GetCollection("channels").UpdateOne(
+		context.TODO(),
+		bson.M{"_id": channelID},
+		bson.M{"$inc": bson.M{"follower_count": delta}},
+	)
+	return err
+}
+
+// CreatePost publishes a post to the channel. Post IDs are ObjectIDs minted
+// here so they sort in publishing order, which the feed cursor relies on.
+func CreatePost(channel Channel, authorID string, post Post) (Post, error) {
+	var views int64
+	post.ID = primitive.NewObjectID().Hex()
+	post.ChannelID = channel.ID
+	post.AuthorID = authorID
+	post.CreatedAt = time.Now().Format(time.RFC3339)
+	post.ViewCount = &views
+
+	_, err := db.GetCollection("channel_posts").InsertOne(context.TODO(), post)
+	if err == nil && post.MediaID != "" && media.IsOwner(authorID, post.MediaID) {
+		// Anyone can read a channel's feed, so its media is public too. Only the
+		// author's own uploads are published; callers check that before posting.
+		media.MakePublic(post.MediaID)
+	}
+	if !channel.ViewCounts {
+		post.ViewCount = nil
+	}
+	return post, err
+}
+
+// FollowersAmong returns which of userIDs follow the channel. Fan-out uses it
+// on the users currently online, so the cost tracks online users rather than
+// the channel's total following.
+func FollowersAmong(channelID string, userIDs []string) ([]string, error) {
+	cursor, err := db.GetCollection("channel_followers").Find(context.TODO(), bson.M{
+		"channel_id": channelID,
+		"user_id":    bson.M{"$in": userIDs},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var followers []Follower
+	if err := cursor.All(context.TODO(), &followers); err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, follower := range followers {
+		ids = append(ids, follower.UserID)
+	}
+	return ids, nil
+}
@@ -0,0 +1,41 @@
+package channel
+
+import (
+	"context"
+	"fmt"
+
+	"gochat_server/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureIndexes creates the indexes the channel collections rely on
+func EnsureIndexes() {
+	indexes := map[string][]mongo.IndexModel{
+		"channel_followers": {
+			{
+				Keys:    bson.D{{Key: "channel_id", Value: 1}, {Key: "user_id", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		},
+		"channel_posts": {
+			{Keys: bson.D{{Key: "channel_id", Value: 1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "media_id", Value: 1}}, Options: options.Index().SetSparse(true)},
+		},
+		"channel_post_views": {
+			{
+				Keys:    bson.D{{Key: "post_id", Value: 1}, {Key: "user_id", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+		},
+	}
+
+	for collection, models := range indexes {
+		if _, err := db.GetCollection(collection).Indexes().CreateMany(context.TODO(), models); err != nil {
+			fmt.Printf("Failed to create indexes on %s: %v\n", collection, err)
+		}
+	}
+}
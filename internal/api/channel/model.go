@@ -0,0 +1,49 @@
+package channel
+
+// Channel is a one-to-many feed: admins post, followers read. Followers are
+// kept in their own collection so a channel can have any number of them.
+type Channel struct {
+	ID            string   `bson:"_id" json:"_id"`
+	Name          string   `bson:"name" json:"name"`
+	Description   string   `bson:"description" json:"description"`
+	Icon          string   `bson:"icon" json:"icon"`
+	CreatedBy     string   `bson:"created_by" json:"created_by"`
+	CreatedAt     string   `bson:"created_at" json:"created_at"`
+	UpdatedAt     string   `bson:"updated_at" json:"updated_at"`
+	Admins        []string `bson:"admins" json:"admins"`
+	FollowerCount int64    `bson:"follower_count" json:"follower_count"`
+	ViewCounts    bool     `bson:"view_counts" json:"view_counts"` // Record and show per-post view counts
+}
+
+type Follower struct {
+	ChannelID  string `bson:"channel_id" json:"channel_id"`
+	UserID     string `bson:"user_id" json:"user_id"`
+	FollowedAt string `bson:"followed_at" json:"followed_at"`
+}
+
+// Post is a message published to a channel. Followers fetch posts from the
+// channel feed rather than getting a copy each.
+type Post struct {
+	ID        string `bson:"_id" json:"_id"`
+	ChannelID string `bson:"channel_id" json:"channel_id"`
+	AuthorID  string `bson:"author_id" json:"author_id"`
+	Content   string `bson:"content" json:"content"`
+	Type      string `bson:"type" json:"type"`
+	MediaID   string `bson:"media_id,omitempty" json:"media_id,omitempty"`
+	CreatedAt string `bson:"created_at" json:"created_at"`
+	ViewCount *int64 `bson:"view_count" json:"view_count,omitempty"`
+}
+
+type CreateChannelRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+	ViewCounts  bool   `json:"view_counts"`
+}
+
+type UpdateChannelRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Icon        *string `json:"icon"`
+	ViewCounts  *bool   `json:"view_counts"`
+}
@@ -1,12 +1,43 @@
 package chat
 
 import (
-	"github.com/gin-gonic/gin"
+	"context"
 	"net/http"
+	"time"
+
+	"gochat_server/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 // GetChatsHandler retrieves a list of chats
 func GetChatsHandler(c *gin.Context) {
 	// Logic to fetch user chats
-	c.JSON(http.StatusOK, gin.H{"message": "Fetched chats successfully"})
-}
\ No newline at end of file
+	userID := c.Query("user_id")
+
+	unreadMentions := map[string]int{}
+	if userID != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		cursor, err := db.GetCollection("chat_mentions").Find(ctx, bson.M{"user_id": userID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch chats"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			var counter MentionCounter
+			if err := cursor.Decode(&counter); err == nil {
+				unreadMentions[counter.ChatID] = counter.Unread
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "Fetched chats successfully",
+		"unread_mentions": unreadMentions,
+	})
+}
@@ -8,3 +8,11 @@ type Chat struct {
 	Messages  []string `json:"messages"`
 	CreatedAt string   `json:"created_at"`
 }
+
+// MentionCounter counts a user's unread @mentions in one group chat
+type MentionCounter struct {
+	UserID        string `bson:"user_id" json:"user_id"`
+	ChatID        string `bson:"chat_id" json:"chat_id"`
+	Unread        int    `bson:"unread" json:"unread"`
+	LastMessageID string `bson:"last_message_id" json:"last_message_id"`
+}
@@ -0,0 +1,292 @@
+package community
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gochat_server/internal/api/group"
+	"gochat_server/internal/api/media"
+	"gochat_server/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Create a community with its announcement group, optionally linking
+// existing groups the caller administers
+func CreateCommunity(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing user_id"})
+		return
+	}
+
+	var request CreateCommunityRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if request.Icon != "" && !media.IsOwnImage(userID, request.Icon) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "icon must be the ID of an image you uploaded"})
+		return
+	}
+
+	var groups []group.Group
+	for _, groupID := range request.GroupIDs {
+		g, status, errMsg := linkableGroup(groupID, userID)
+		if errMsg != "" {
+			c.JSON(status, gin.H{"error": errMsg})
+			return
+		}
+		groups = append(groups, g)
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	community := Community{
+		ID:          primitive.NewObjectID().Hex(),
+		Name:        request.Name,
+		Description: request.Description,
+		Icon:        request.Icon,
+		CreatedBy:   userID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		GroupIDs:    []string{},
+		Members:     []CommunityMember{{UserID: userID, IsAdmin: true, JoinedAt: now}},
+	}
+
+	announcements, err := group.CreateAnnouncementGroup(request.Name, userID, community.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create announcement group"})
+		return
+	}
+	community.AnnouncementGroupID = announcements.ID
+
+	if _, err := db.GetCollection("communities").InsertOne(context.TODO(), community); err != nil {
+		if err := group.Delete(announcements.ID); err != nil {
+			fmt.Println("Failed to delete orphaned announcement group:", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create community"})
+		return
+	}
+	if community.Icon != "" {
+		media.MakePublic(community.Icon)
+	}
+
+	for _, g := range groups {
+		if err := linkGroup(community, g, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link group " + g.ID})
+			return
+		}
+	}
+
+	community, _ = FindCommunity(community.ID)
+	c.JSON(http.StatusCreated, gin.H{"message": "Community created successfully", "community": community})
+}
+
+// Get a community and a summary of its groups (members only)
+func GetCommunity(c *gin.Context) {
+	community, ok := loadCommunityAsMember(c)
+	if !ok {
+		return
+	}
+
+	groupIDs := append([]string{community.AnnouncementGroupID}, community.GroupIDs...)
+	summaries := []GroupSummary{}
+	for _, groupID := range groupIDs {
+		g, err := group.FindGroup(groupID)
+		if err != nil {
+			continue
+		}
+		summaries = append(summaries, GroupSummary{
+			ID:             g.ID,
+			Title:          g.Title,
+			GroupIcon:      g.GroupIcon,
+			MemberCount:    len(g.Members),
+			IsAnnouncement: g.IsAnnouncement,
+		})
+	}
+
+	community.Members = nil
+	c.JSON(http.StatusOK, gin.H{"community": community, "groups": summaries})
+}
+
+// List the community's members (members only)
+func GetCommunityMembers(c *gin.Context) {
+	community, ok := loadCommunityAsMember(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"members": community.Members, "member_count": len(community.Members)})
+}
+
+// Link an existing group to the community. The caller must administer both;
+// the group's members join the community.
+func AddCommunityGroup(c *gin.Context) {
+	userID := c.Query("user_id")
+	community, ok := loadCommunityAsAdmin(c)
+	if !ok {
+		return
+	}
+
+	g, status, errMsg := linkableGroup(c.Param("group_id"), userID)
+	if errMsg != "" {
+		c.JSON(status, gin.H{"error": errMsg})
+		return
+	}
+
+	if err := linkGroup(community, g, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add group to community"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Group added to community"})
+}
+
+// Unlink a group from the community (community admins only). Members who are
+// left without any group in the community leave the community too.
+func RemoveCommunityGroup(c *gin.Context) {
+	community, ok := loadCommunityAsAdmin(c)
+	if !ok {
+		return
+	}
+
+	groupID := c.Param("group_id")
+	if !community.hasGroup(groupID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group is not part of this community"})
+		return
+	}
+
+	if err := unlinkGroup(community, groupID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove group from community"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Group removed from community"})
+}
+
+// Leave the community, along with every group in it
+func LeaveCommunity(c *gin.Context) {
+	userID := c.Query("user_id")
+	community, ok := loadCommunityAsMember(c)
+	if !ok {
+		return
+	}
+
+	if community.IsAdmin(userID) && community.adminCount() == 1 && len(community.Members) > 1 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The last community admin can't leave while others remain"})
+		return
+	}
+
+	for _, groupID := range community.GroupIDs {
+		if err := group.Leave(groupID, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave community group " + groupID})
+			return
+		}
+	}
+	if err := removeCommunityMember(community, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave community"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User left the community"})
+}
+
+// MemberJoinedGroup is called when someone joins a group that belongs to a
+// community, making them a member of the community as well
+func MemberJoinedGroup(communityID, userID string) {
+	community, err := FindCommunity(communityID)
+	if err != nil {
+		return
+	}
+	addCommunityMember(community, userID, userID)
+}
+
+// MemberLeftGroup is called when someone leaves, or is removed from, a group
+// that belongs to a community. Once they are in none of its groups they leave
+// the community and its announcement group; community admins stay, as when a
+// group is unlinked.
+func MemberLeftGroup(communityID, userID string) {
+	community, err := FindCommunity(communityID)
+	if err != nil {
+		return
+	}
+	if _, ok := community.GetMember(userID); !ok || community.IsAdmin(userID) {
+		return
+	}
+	if inGroup, err := inCommunityGroup(community.GroupIDs, userID); err != nil || inGroup {
+		return
+	}
+	if err := removeCommunityMember(community, userID); err != nil {
+		fmt.Println("Failed to remove community member:", err)
+	}
+}
+
+// GroupDeleted is called when a group that belongs to a community is deleted.
+// It comes off the community, and its members leave the community as if the
+// group had been unlinked.
+func GroupDeleted(g group.Group) {
+	community, err := FindCommunity(g.CommunityID)
+	if err != nil || !community.hasGroup(g.ID) {
+		return
+	}
+	if err := detachGroup(community, g); err != nil {
+		fmt.Println("Failed to remove deleted group from community:", err)
+	}
+}
+
+// loadCommunityAsMember loads the :id community and checks the caller belongs
+// to it, writing the error response when not
+func loadCommunityAsMember(c *gin.Context) (Community, bool) {
+	community, err := FindCommunity(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Community not found"})
+		return Community{}, false
+	}
+	if _, ok := community.GetMember(c.Query("user_id")); !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this community"})
+		return Community{}, false
+	}
+	return community, true
+}
+
+// loadCommunityAsAdmin loads the :id community and checks the caller is one of
+// its admins, writing the error response when not
+func loadCommunityAsAdmin(c *gin.Context) (Community, bool) {
+	community, err := FindCommunity(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Community not found"})
+		return Community{}, false
+	}
+	if !community.IsAdmin(c.Query("user_id")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only community admins can manage groups"})
+		return Community{}, false
+	}
+	return community, true
+}
+
+// linkableGroup loads a group that userID may link into a community
+func linkableGroup(groupID, userID string) (group.Group, int, string) {
+	g, err := group.FindGroup(groupID)
+	if err != nil {
+		return group.Group{}, http.StatusNotFound, "Group not found"
+	}
+	if !g.IsAdmin(userID) {
+		return group.Group{}, http.StatusForbidden, "Only group admins can add a group to a community"
+	}
+	if g.CommunityID != "" || g.IsAnnouncement {
+		return group.Group{}, http.StatusConflict, "Group already belongs to a community"
+	}
+	return g, 0, ""
+}
+
+// FindCommunity loads a community by its ID
+func FindCommunity(communityID string) (Community, error) {
+	var community Community
+	err := db.GetCollection("communities").FindOne(context.TODO(), bson.M{"_id": communityID}).Decode(&community)
+	return community, err
+}
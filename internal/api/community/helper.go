@@ -0,0 +1,175 @@
+package community
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gochat_server/internal/api/group"
+	"gochat_server/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// GetMember returns the membership entry for userID, if they are in the community
+func (c Community) GetMember(userID string) (CommunityMember, bool) {
+	for _, member := range c.Members {
+		if member.UserID == userID {
+			return member, true
+		}
+	}
+	return CommunityMember{}, false
+}
+
+// IsAdmin reports whether userID is an admin of the community
+func (c Community) IsAdmin(userID string) bool {
+	member, ok := c.GetMember(userID)
+	return ok && member.IsAdmin
+}
+
+func (c Community) adminCount() int {
+	count := 0
+	for _, member := range c.Members {
+		if member.IsAdmin {
+			count++
+		}
+	}
+	return count
+}
+
+func (c Community) hasGroup(groupID string) bool {
+	for _, id := range c.GroupIDs {
+		if id == groupID {
+			return true
+		}
+	}
+	return false
+}
+
+// linkGroup attaches g to the community and brings its members in
+func linkGroup(community Community, g group.Group, actorID string) error {
+	if err := group.SetCommunity(g.ID, community.ID); err != nil {
+		return err
+	}
+	_, err := db.GetCollection("communities").UpdateOne(
+		context.TODO(),
+		bson.M{"_id": community.ID},
+		bson.M{
+			"$addToSet": bson.M{"group_ids": g.ID},
+			"$set":      bson.M{"updated_at": time.Now().Format(time.RFC3339)},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, member := range g.Members {
+		addCommunityMember(community, member.UserID, actorID)
+	}
+	return nil
+}
+
+// unlinkGroup detaches a group from the community. Its members who don't
+// belong to any other group in the community (and aren't community admins)
+// leave the community.
+func unlinkGroup(community Community, groupID string) error {
+	if err := group.SetCommunity(groupID, ""); err != nil {
+		return err
+	}
+	g, err := group.FindGroup(groupID)
+	if err != nil {
+		g = group.Group{ID: groupID}
+	}
+	return detachGroup(community, g)
+}
+
+// detachGroup takes g off the community's groups and its members out of the
+// community the way unlinkGroup does, once g no longer points at it
+func detachGroup(community Community, g group.Group) error {
+	_, err := db.GetCollection("communities").UpdateOne(
+		context.TODO(),
+		bson.M{"_id": community.ID},
+		bson.M{
+			"$pull": bson.M{"group_ids": g.ID},
+			"$set":  bson.M{"updated_at": time.Now().Format(time.RFC3339)},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	var remaining []string
+	for _, id := range community.GroupIDs {
+		if id != g.ID {
+			remaining = append(remaining, id)
+		}
+	}
+
+	for _, member := range g.Members {
+		if community.IsAdmin(member.UserID) {
+			continue
+		}
+		if inGroup, err := inCommunityGroup(remaining, member.UserID); err == nil && !inGroup {
+			removeCommunityMember(community, member.UserID)
+		}
+	}
+	return nil
+}
+
+// inCommunityGroup reports whether userID is a member of any of the groups
+func inCommunityGroup(groupIDs []string, userID string) (bool, error) {
+	if len(groupIDs) == 0 {
+		return false, nil
+	}
+	count, err := db.GetCollection("groups").CountDocuments(context.TODO(), bson.M{
+		"_id":             bson.M{"$in": groupIDs},
+		"members.user_id": userID,
+	})
+	return count > 0, err
+}
+
+// addCommunityMember adds userID to the community and its announcement group
+func addCommunityMember(community Community, userID, actorID string) {
+	if _, ok := community.GetMember(userID); ok {
+		return
+	}
+
+	_, err := db.GetCollection("communities").UpdateOne(
+		context.TODO(),
+		bson.M{"_id": community.ID, "members.user_id": bson.M{"$ne": userID}},
+		bson.M{
+			"$push": bson.M{"members": CommunityMember{UserID: userID, JoinedAt: time.Now().Format(time.RFC3339)}},
+			"$set":  bson.M{"updated_at": time.Now().Format(time.RFC3339)},
+		},
+	)
+	if err != nil {
+		fmt.Println("Failed to add community member:", err)
+		return
+	}
+
+	announcements, err := group.FindGroup(community.AnnouncementGroupID)
+	if err != nil {
+		fmt.Println("Error loading announcement group:", err)
+		return
+	}
+	if err := group.AddMember(announcements, userID, actorID); err != nil {
+		fmt.Println("Failed to add member to announcement group:", err)
+	}
+}
+
+// removeCommunityMember takes userID out of the community and its
+// announcement group
+func removeCommunityMember(community Community, userID string) error {
+	_, err := db.GetCollection("communities").UpdateOne(
+		context.TODO(),
+		bson.M{"_id": community.ID},
+		bson.M{
+			"$pull": bson.M{"members": bson.M{"user_id": userID}},
+			"$set":  bson.M{"updated_at": time.Now().Format(time.RFC3339)},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return group.Leave(community.AnnouncementGroupID, userID)
+}
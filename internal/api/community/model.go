@@ -0,0 +1,38 @@
+package community
+
+type CommunityMember struct {
+	UserID   string `bson:"user_id" json:"user_id"`
+	IsAdmin  bool   `bson:"is_admin" json:"is_admin"`
+	JoinedAt string `bson:"joined_at" json:"joined_at"`
+}
+
+// Community groups related groups under one umbrella. Every member of a
+// linked group is a community member and belongs to the announcement group.
+type Community struct {
+	ID                  string            `bson:"_id" json:"_id"`
+	Name                string            `bson:"name" json:"name"`
+	Description         string            `bson:"description" json:"description"`
+	Icon                string            `bson:"icon" json:"icon"`
+	CreatedBy           string            `bson:"created_by" json:"created_by"`
+	CreatedAt           string            `bson:"created_at" json:"created_at"`
+	UpdatedAt           string            `bson:"updated_at" json:"updated_at"`
+	AnnouncementGroupID string            `bson:"announcement_group_id" json:"announcement_group_id"`
+	GroupIDs            []string          `bson:"group_ids" json:"group_ids"`
+	Members             []CommunityMember `bson:"members" json:"members"`
+}
+
+type CreateCommunityRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	Icon        string   `json:"icon"`
+	GroupIDs    []string `json:"group_ids"` // Existing groups to link straight away
+}
+
+// GroupSummary is how a linked group is listed on a community
+type GroupSummary struct {
+	ID             string `json:"_id"`
+	Title          string `json:"title"`
+	GroupIcon      string `json:"group_icon"`
+	MemberCount    int    `json:"member_count"`
+	IsAnnouncement bool   `json:"is_announcement"`
+}
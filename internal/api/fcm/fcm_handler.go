@@ -69,14 +69,31 @@ func getAccessToken() (string, error) {
 	return accessToken, nil
 }
 
-func sendNotification(deviceToken string) error {
-	token, err := getAccessToken()
+// SendFCMMentionNotification sends a visible push for an @mention. It is
+// used for users who muted the chat, whose clients otherwise stay silent.
+func SendFCMMentionNotification(receiverID, title, body string, data map[string]string) {
+	deviceToken, err := getDeviceTokenForUser(receiverID)
 	if err != nil {
-		return fmt.Errorf("failed to get access token: %v", err)
+		fmt.Printf("Failed to get token for %s: %v\n", receiverID, err)
+		return
 	}
 
-	fcmURL := "https://fcm.googleapis.com/v1/projects/whatsapp-clone-77193/messages:send"
+	err = postFCMMessage(map[string]interface{}{
+		"message": map[string]interface{}{
+			"token": deviceToken,
+			"data":  data,
+			"notification": map[string]string{
+				"title": title,
+				"body":  body,
+			},
+		},
+	})
+	if err != nil {
+		fmt.Printf("Failed to send mention notification for %s: %v\n", receiverID, err)
+	}
+}
 
+func sendNotification(deviceToken string) error {
 	// Convert messageContent struct into map[string]string with nested struct handling
 	// msgMap := structToMap(messageContent)
 
@@ -92,6 +109,18 @@ func sendNotification(deviceToken string) error {
 		},
 	}
 
+	return postFCMMessage(fcmMessage)
+}
+
+// postFCMMessage sends a message to the FCM HTTP v1 API
+func postFCMMessage(fcmMessage map[string]interface{}) error {
+	token, err := getAccessToken()
+	if err != nil {
+		return fmt.Errorf("failed to get access token: %v", err)
+	}
+
+	fcmURL := "https://fcm.googleapis.com/v1/projects/whatsapp-clone-77193/messages:send"
+
 	messageBytes, err := json.Marshal(fcmMessage)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %v", err)
@@ -0,0 +1,120 @@
+package file
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// sniffLen is how much of a file DetectMimeType looks at
+const sniffLen = 512
+
+// isoBrands maps the major brand of an ISO base media file (the "ftyp" box)
+// onto its MIME type, for the brands http.DetectContentType doesn't know
+var isoBrands = map[string]string{
+	"heic": "image/heic",
+	"heix": "image/heic",
+	"heim": "image/heic",
+	"heis": "image/heic",
+	"hevc": "image/heic-sequence",
+	"hevx": "image/heic-sequence",
+	"mif1": "image/heif",
+	"msf1": "image/heif-sequence",
+	"avif": "image/avif",
+	"qt  ": "video/quicktime",
+	"M4A ": "audio/mp4",
+	"M4B ": "audio/mp4",
+	"3gp4": "video/3gpp",
+	"3gp5": "video/3gpp",
+	"3g2a": "video/3gpp2",
+}
+
+// DetectMimeType sniffs the MIME type from the first bytes of a file. It
+// knows the formats phones produce that http.DetectContentType misses, and
+// falls back to it for everything else.
+func DetectMimeType(data []byte) string {
+	switch {
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return "image/webp"
+	case len(data) >= 12 && string(data[4:8]) == "ftyp":
+		if mime, ok := isoBrands[string(data[8:12])]; ok {
+			return mime
+		}
+	case len(data) >= 36 && string(data[:4]) == "OggS" && string(data[28:36]) == "OpusHead":
+		return "audio/ogg; codecs=opus"
+	case len(data) >= 35 && string(data[:4]) == "OggS" && string(data[29:35]) == "vorbis":
+		return "audio/ogg"
+	case bytes.HasPrefix(data, []byte("#!AMR-WB\n")):
+		return "audio/amr-wb"
+	case bytes.HasPrefix(data, []byte("#!AMR\n")):
+		return "audio/amr"
+	case bytes.HasPrefix(data, []byte("fLaC")):
+		return "audio/flac"
+	case bytes.HasPrefix(data, []byte("\x7fELF")):
+		return "application/x-elf"
+	case bytes.HasPrefix(data, []byte("MZ")):
+		return "application/x-msdownload"
+	case bytes.HasPrefix(data, []byte("#!")):
+		return "application/x-sh"
+	}
+	return http.DetectContentType(data)
+}
+
+// DetectFileMimeType sniffs the MIME type of the file at path. Zip archives
+// are opened to tell Office documents and Android packages from plain zips.
+func DetectFileMimeType(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	head := make([]byte, sniffLen)
+	n, _ := f.Read(head)
+	f.Close()
+
+	mime := DetectMimeType(head[:n])
+	if mime == "application/zip" {
+		mime = detectZipMimeType(path)
+	}
+	return mime, nil
+}
+
+// detectZipMimeType looks at the entries of a zip archive for the layout of
+// an OOXML document or an APK
+func detectZipMimeType(path string) string {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return "application/zip"
+	}
+	defer archive.Close()
+
+	var contentTypes bool
+	var prefix string
+	for _, entry := range archive.File {
+		switch {
+		case entry.Name == "AndroidManifest.xml":
+			return "application/vnd.android.package-archive"
+		case entry.Name == "[Content_Types].xml":
+			contentTypes = true
+		case prefix == "":
+			for _, p := range []string{"word/", "xl/", "ppt/"} {
+				if strings.HasPrefix(entry.Name, p) {
+					prefix = p
+				}
+			}
+		}
+	}
+
+	if contentTypes {
+		switch prefix {
+		case "word/":
+			return "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+		case "xl/":
+			return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+		case "ppt/":
+			return "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+		}
+	}
+	return "application/zip"
+}
@@ -1,37 +1,33 @@
 package file
 
 import (
+	"fmt"
 	"image"
 	_ "image/jpeg"
 	_ "image/png"
-	"net/http"
 	"os"
 	"os/exec"
+	"strings"
 
 	"github.com/buckket/go-blurhash"
 )
 
-func detectMimeType(data []byte) string {
-	return http.DetectContentType(data)
-}
-
-func classifyMediaType(mime string) string {
-	if len(mime) == 0 {
-		return "document"
-	}
+// ClassifyMediaType maps a MIME type onto image, video, audio or document
+func ClassifyMediaType(mime string) string {
 	switch {
-	case mime[:6] == "image/":
+	case strings.HasPrefix(mime, "image/"):
 		return "image"
-	case mime[:6] == "video/":
+	case strings.HasPrefix(mime, "video/"):
 		return "video"
-	case mime[:6] == "audio/":
+	case strings.HasPrefix(mime, "audio/"):
 		return "audio"
 	default:
 		return "document"
 	}
 }
 
-func generateBlurHash(img image.Image) (string, int, int, error) {
+// GenerateBlurHash returns the blurhash and dimensions of an image
+func GenerateBlurHash(img image.Image) (string, int, int, error) {
 	width := img.Bounds().Dx()
 	height := img.Bounds().Dy()
 
@@ -64,7 +60,9 @@ func extractVideoThumbnail(videoPath string) (string, error) {
 	return tmpFile.Name(), nil
 }
 
-func blurHashFromVideo(videoPath string) (string, int, int, error) {
+// BlurHashFromVideo returns the blurhash and dimensions of an early frame of
+// the video at videoPath
+func BlurHashFromVideo(videoPath string) (string, int, int, error) {
 	thumbPath, err := extractVideoThumbnail(videoPath)
 	if err != nil {
 		return "", 0, 0, err
@@ -92,3 +90,31 @@ func blurHashFromVideo(videoPath string) (string, int, int, error) {
 
 	return hash, w, h, nil
 }
+
+// GenerateThumbnail renders a JPEG of the image or video at srcPath (an early
+// frame, for videos) fitted within size x size pixels, without upscaling. The
+// caller removes the returned file.
+func GenerateThumbnail(srcPath string, size int, isVideo bool) (string, error) {
+	tmpFile, err := os.CreateTemp("", "thumb-*.jpg")
+	if err != nil {
+		return "", err
+	}
+	tmpFile.Close()
+
+	args := []string{"-y", "-i", srcPath}
+	if isVideo {
+		args = append(args, "-ss", "0.5")
+	}
+	args = append(args,
+		"-frames:v", "1",
+		"-vf", fmt.Sprintf("scale='min(%d,iw)':'min(%d,ih)':force_original_aspect_ratio=decrease", size, size),
+		"-q:v", "4",
+		tmpFile.Name(),
+	)
+
+	if err := exec.Command("ffmpeg", args...).Run(); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", err
+	}
+	return tmpFile.Name(), nil
+}
@@ -0,0 +1,24 @@
+package file
+
+import "testing"
+
+func TestClassifyMediaType(t *testing.T) {
+	tests := []struct {
+		mime string
+		want string
+	}{
+		{"image/png", "image"},
+		{"image/svg+xml", "image"},
+		{"video/mp4", "video"},
+		{"audio/ogg", "audio"},
+		{"application/pdf", "document"},
+		{"text/plain", "document"},
+		{"", "document"},
+		{"imagery/png", "document"},
+	}
+	for _, tt := range tests {
+		if got := ClassifyMediaType(tt.mime); got != tt.want {
+			t.Errorf("ClassifyMediaType(%q) = %q, want %q", tt.mime, got, tt.want)
+		}
+	}
+}
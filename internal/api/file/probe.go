@@ -0,0 +1,158 @@
+package file
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+)
+
+// WaveformBuckets is how many amplitude samples a voice note's waveform has
+const WaveformBuckets = 64
+
+// waveformRate is the sample rate audio is decoded at for the waveform, and
+// waveformBlock how many of those samples are folded together while decoding
+const (
+	waveformRate  = 8000
+	waveformBlock = waveformRate / 100
+)
+
+// ProbeResult is what ffprobe reports about an audio or video file
+type ProbeResult struct {
+	Duration float64 // seconds
+	Codec    string  // of the video stream, or the audio stream for audio files
+	Bitrate  int64   // bits per second, over the whole file
+	Width    int     // of the video stream
+	Height   int
+}
+
+// ProbeMedia runs ffprobe on the file at path
+func ProbeMedia(path string) (ProbeResult, error) {
+	output, err := exec.Command(
+		"ffprobe",
+		"-v", "error",
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
+		path,
+	).Output()
+	if err != nil {
+		return ProbeResult{}, err
+	}
+
+	var probe struct {
+		Format struct {
+			Duration string `json:"duration"`
+			BitRate  string `json:"bit_rate"`
+		} `json:"format"`
+		Streams []struct {
+			CodecType string `json:"codec_type"`
+			CodecName string `json:"codec_name"`
+			Width     int    `json:"width"`
+			Height    int    `json:"height"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return ProbeResult{}, err
+	}
+
+	var result ProbeResult
+	result.Duration, _ = strconv.ParseFloat(probe.Format.Duration, 64)
+	result.Bitrate, _ = strconv.ParseInt(probe.Format.BitRate, 10, 64)
+	for _, stream := range probe.Streams {
+		if stream.CodecType == "video" {
+			result.Codec = stream.CodecName
+			result.Width, result.Height = stream.Width, stream.Height
+			break
+		}
+		if stream.CodecType == "audio" && result.Codec == "" {
+			result.Codec = stream.CodecName
+		}
+	}
+	return result, nil
+}
+
+// GenerateWaveform decodes the audio at path to mono PCM and returns its
+// loudness in the given number of buckets, scaled so the loudest is 100
+func GenerateWaveform(path string, buckets int) ([]int, error) {
+	cmd := exec.Command(
+		"ffmpeg",
+		"-v", "error",
+		"-i", path,
+		"-vn",
+		"-ac", "1",
+		"-ar", strconv.Itoa(waveformRate),
+		"-f", "s16le",
+		"-",
+	)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	// Keep the mean square of every 10ms block rather than every sample, so a
+	// long recording doesn't have to be held in memory
+	var blocks []float64
+	var sum float64
+	var count int
+	reader := bufio.NewReader(stdout)
+	sample := make([]byte, 2)
+	for {
+		if _, err := io.ReadFull(reader, sample); err != nil {
+			break
+		}
+		value := float64(int16(binary.LittleEndian.Uint16(sample))) / math.MaxInt16
+		sum += value * value
+		count++
+		if count == waveformBlock {
+			blocks = append(blocks, sum/float64(count))
+			sum, count = 0, 0
+		}
+	}
+	if count > 0 {
+		blocks = append(blocks, sum/float64(count))
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, err
+	}
+
+	return bucketWaveform(blocks, buckets), nil
+}
+
+// bucketWaveform spreads the blocks evenly over the buckets, takes the RMS of
+// each and normalizes them to 0-100
+func bucketWaveform(blocks []float64, buckets int) []int {
+	waveform := make([]int, buckets)
+	if len(blocks) == 0 {
+		return waveform
+	}
+
+	levels := make([]float64, buckets)
+	var loudest float64
+	for i := range levels {
+		start := i * len(blocks) / buckets
+		// With fewer blocks than buckets, neighbouring buckets share a block
+		end := max((i+1)*len(blocks)/buckets, start+1)
+
+		var total float64
+		for _, block := range blocks[start:end] {
+			total += block
+		}
+		levels[i] = math.Sqrt(total / float64(end-start))
+		loudest = max(loudest, levels[i])
+	}
+
+	if loudest == 0 {
+		return waveform
+	}
+	for i, level := range levels {
+		waveform[i] = int(math.Round(level / loudest * 100))
+	}
+	return waveform
+}
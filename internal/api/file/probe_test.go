@@ -0,0 +1,28 @@
+package file
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBucketWaveform(t *testing.T) {
+	tests := []struct {
+		name    string
+		blocks  []float64
+		buckets int
+		want    []int
+	}{
+		{"no audio", nil, 4, []int{0, 0, 0, 0}},
+		{"silence", []float64{0, 0, 0, 0}, 2, []int{0, 0}},
+		{"one block per bucket", []float64{0.25, 1, 0.0625, 0}, 4, []int{50, 100, 25, 0}},
+		{"blocks folded together", []float64{1, 1, 0.25, 0.25}, 2, []int{100, 50}},
+		{"fewer blocks than buckets", []float64{1, 0.25}, 4, []int{100, 100, 50, 50}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bucketWaveform(tt.blocks, tt.buckets); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("bucketWaveform() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package file
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// TranscodeVideo re-encodes the video at srcPath as an H.264/AAC MP4 whose
+// shorter side is at most maxSide pixels, and returns the path of the result.
+// progress, if set, is called with the percentage done as ffmpeg reports it;
+// duration (seconds) is what the percentage is worked out against.
+func TranscodeVideo(srcPath string, maxSide int, duration float64, progress func(int)) (string, error) {
+	tmpFile, err := os.CreateTemp("", "transcode-*.mp4")
+	if err != nil {
+		return "", err
+	}
+	tmpFile.Close()
+
+	// Cap the shorter side so portrait and landscape clips get the same
+	// quality; -2 keeps the other side even, as H.264 requires
+	scale := fmt.Sprintf(
+		"scale='if(gt(iw,ih),-2,min(%[1]d,iw))':'if(gt(iw,ih),min(%[1]d,ih),-2)'",
+		maxSide,
+	)
+	cmd := exec.Command(
+		"ffmpeg",
+		"-y",
+		"-v", "error",
+		"-i", srcPath,
+		"-vf", scale,
+		"-c:v", "libx264",
+		"-preset", "veryfast",
+		"-crf", "23",
+		"-profile:v", "high",
+		"-pix_fmt", "yuv420p",
+		"-c:a", "aac",
+		"-b:a", "128k",
+		"-movflags", "+faststart",
+		"-progress", "pipe:1",
+		"-nostats",
+		tmpFile.Name(),
+	)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return "", err
+	}
+	if err := cmd.Start(); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", err
+	}
+
+	// -progress writes key=value lines; out_time_us is how far into the
+	// output ffmpeg has got
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "out_time_us=")
+		if !ok || progress == nil || duration <= 0 {
+			continue
+		}
+		if us, err := strconv.ParseInt(value, 10, 64); err == nil && us >= 0 {
+			progress(min(int(float64(us)/1e6/duration*100), 99))
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", err
+	}
+	if progress != nil {
+		progress(100)
+	}
+	return tmpFile.Name(), nil
+}
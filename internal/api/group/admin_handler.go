@@ -0,0 +1,166 @@
+package group
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"gochat_server/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Make a member an admin (admins only)
+func PromoteMember(c *gin.Context) {
+	group, target, ok := loadMemberAsAdmin(c)
+	if !ok {
+		return
+	}
+	if target.IsAdmin {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is already an admin"})
+		return
+	}
+
+	if err := setAdmin(group.ID, target.UserID, true); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to promote member"})
+		return
+	}
+	emitSystemMessage(group.ID, SystemEvent{Action: ActionAdminPromoted, ActorID: c.Query("user_id"), TargetID: target.UserID})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member promoted to admin"})
+}
+
+// Take admin rights away from a member (admins only). The creator can't be
+// demoted, and a group always keeps at least one admin.
+func DemoteMember(c *gin.Context) {
+	group, target, ok := loadMemberAsAdmin(c)
+	if !ok {
+		return
+	}
+	if !target.IsAdmin {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is not an admin"})
+		return
+	}
+	if target.UserID == group.CreatedBy {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The group owner can't be demoted; transfer ownership first"})
+		return
+	}
+	if group.adminCount() == 1 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "A group must keep at least one admin"})
+		return
+	}
+
+	if err := setAdmin(group.ID, target.UserID, false); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to demote admin"})
+		return
+	}
+	emitSystemMessage(group.ID, SystemEvent{Action: ActionAdminDemoted, ActorID: c.Query("user_id"), TargetID: target.UserID})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Admin demoted to member"})
+}
+
+// Remove a member from the group (admins only). The creator can't be removed.
+func RemoveMember(c *gin.Context) {
+	group, target, ok := loadMemberAsAdmin(c)
+	if !ok {
+		return
+	}
+	if target.UserID == group.CreatedBy {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The group owner can't be removed"})
+		return
+	}
+
+	if err := removeMember(group.ID, target.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+	emitSystemMessage(group.ID, SystemEvent{Action: ActionMemberRemoved, ActorID: c.Query("user_id"), TargetID: target.UserID})
+	memberLeft(group, target.UserID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed from group"})
+}
+
+// Hand ownership of the group to another member (owner only). The new owner
+// is made an admin.
+func TransferOwnership(c *gin.Context) {
+	groupID := c.Param("id")
+	userID := c.Query("user_id")
+	memberID := c.Param("member_id")
+
+	group, err := FindGroup(groupID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+	if group.CreatedBy != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the group owner can transfer ownership"})
+		return
+	}
+	if _, ok := group.GetMember(memberID); !ok || memberID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New owner must be another member of the group"})
+		return
+	}
+
+	if err := transferOwnership(group.ID, memberID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer ownership"})
+		return
+	}
+	emitSystemMessage(group.ID, SystemEvent{Action: ActionOwnerChanged, ActorID: userID, TargetID: memberID})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Group ownership transferred"})
+}
+
+// loadMemberAsAdmin loads the :id group and its :member_id member, checking
+// that the caller is an admin, and writes the error response when not
+func loadMemberAsAdmin(c *gin.Context) (Group, GroupMember, bool) {
+	group, err := FindGroup(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return Group{}, GroupMember{}, false
+	}
+	if !group.IsAdmin(c.Query("user_id")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can manage members"})
+		return Group{}, GroupMember{}, false
+	}
+	target, ok := group.GetMember(c.Param("member_id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User is not a member of the group"})
+		return Group{}, GroupMember{}, false
+	}
+	return group, target, true
+}
+
+func setAdmin(groupID, userID string, isAdmin bool) error {
+	_, err := db.GetCollection("groups").UpdateOne(
+		context.TODO(),
+		bson.M{"_id": groupID, "members.user_id": userID},
+		bson.M{"$set": bson.M{"members.$.is_admin": isAdmin, "updated_at": time.Now().Format(time.RFC3339)}},
+	)
+	return err
+}
+
+func removeMember(groupID, userID string) error {
+	_, err := db.GetCollection("groups").UpdateOne(
+		context.TODO(),
+		bson.M{"_id": groupID},
+		bson.M{
+			"$pull": bson.M{"members": bson.M{"user_id": userID}},
+			"$set":  bson.M{"updated_at": time.Now().Format(time.RFC3339)},
+		},
+	)
+	return err
+}
+
+func transferOwnership(groupID, userID string) error {
+	_, err := db.GetCollection("groups").UpdateOne(
+		context.TODO(),
+		bson.M{"_id": groupID, "members.user_id": userID},
+		bson.M{"$set": bson.M{
+			"created_by":         userID,
+			"members.$.is_admin": true,
+			"updated_at":         time.Now().Format(time.RFC3339),
+		}},
+	)
+	return err
+}
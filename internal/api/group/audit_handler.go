@@ -0,0 +1,82 @@
+package group
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gochat_server/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// writeAuditEntry records who changed which group settings
+func writeAuditEntry(groupID, actorID string, changes []FieldChange) {
+	entry := AuditEntry{
+		ID:        primitive.NewObjectID().Hex(),
+		GroupID:   groupID,
+		ActorID:   actorID,
+		Changes:   changes,
+		CreatedAt: time.Now().Format(time.RFC3339),
+	}
+	if _, err := db.GetCollection("group_audit_log").InsertOne(context.TODO(), entry); err != nil {
+		fmt.Println("Failed to write group audit log:", err)
+	}
+}
+
+// List a group's settings changes, newest first (admins only). Pass the
+// created_at and _id of the last entry seen as ?before= and ?before_id= to
+// page back; entries made in the same second are told apart by their _id.
+func GetAuditLog(c *gin.Context) {
+	groupID := c.Param("id")
+	userID := c.Query("user_id")
+
+	group, err := FindGroup(groupID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+	if !group.IsAdmin(userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can view the audit log"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	filter := bson.M{"group_id": groupID}
+	if before := c.Query("before"); before != "" {
+		filter["$or"] = bson.A{
+			bson.M{"created_at": bson.M{"$lt": before}},
+			bson.M{"created_at": before, "_id": bson.M{"$lt": c.Query("before_id")}},
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+	cursor, err := db.GetCollection("group_audit_log").Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit log"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	entries := []AuditEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"audit_log": entries})
+}
@@ -0,0 +1,167 @@
+package group
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"gochat_server/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Get the groups the caller belongs to, most recently updated first
+func GetMyGroups(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing user_id"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"members.user_id": userID}}},
+		{{Key: "$sort", Value: bson.M{"updated_at": -1}}},
+		{{Key: "$project", Value: bson.M{
+			"title":           1,
+			"group_icon":      1,
+			"updated_at":      1,
+			"community_id":    1,
+			"is_announcement": 1,
+			"member_count":    bson.M{"$size": "$members"},
+			"self": bson.M{"$arrayElemAt": bson.A{
+				bson.M{"$filter": bson.M{
+					"input": "$members",
+					"cond":  bson.M{"$eq": bson.A{"$$this.user_id", userID}},
+				}},
+				0,
+			}},
+		}}},
+		{{Key: "$addFields", Value: bson.M{
+			"is_admin": bson.M{"$ifNull": bson.A{"$self.is_admin", false}},
+			"muted":    bson.M{"$ifNull": bson.A{"$self.muted", false}},
+		}}},
+	}
+
+	cursor, err := db.GetCollection("groups").Aggregate(ctx, pipeline)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch groups"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	groups := []GroupSummary{}
+	if err := cursor.All(ctx, &groups); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding groups"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"groups": groups})
+}
+
+// Get a page of a group's members (members only), ordered by user ID. Filter
+// by name with ?q=, to admins with ?admins=true, and pass the returned
+// next_cursor as ?cursor= for the following page.
+func GetGroupMembers(c *gin.Context) {
+	groupID := c.Param("id")
+	userID := c.Query("user_id")
+
+	// Check membership without loading the whole members array
+	collection := db.GetCollection("groups")
+	count, err := collection.CountDocuments(context.TODO(), bson.M{"_id": groupID, "members.user_id": userID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+	if count == 0 {
+		if exists, _ := collection.CountDocuments(context.TODO(), bson.M{"_id": groupID}); exists == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+			return
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this group"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	memberFilter := bson.M{}
+	if cursor := c.Query("cursor"); cursor != "" {
+		memberFilter["members.user_id"] = bson.M{"$gt": cursor}
+	}
+	if c.Query("admins") == "true" {
+		memberFilter["members.is_admin"] = true
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"_id": groupID}}},
+		{{Key: "$unwind", Value: "$members"}},
+		{{Key: "$match", Value: memberFilter}},
+		{{Key: "$sort", Value: bson.M{"members.user_id": 1}}},
+	}
+	// Names are only needed for the page unless they are being searched, in
+	// which case every member has to be looked up before the page is cut
+	q := c.Query("q")
+	if q == "" {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: limit}})
+	}
+	pipeline = append(pipeline,
+		bson.D{{Key: "$lookup", Value: bson.M{
+			"from": "users",
+			"let": bson.M{"uid": bson.M{"$convert": bson.M{
+				"input": "$members.user_id", "to": "objectId", "onError": nil, "onNull": nil,
+			}}},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$_id", "$$uid"}}}},
+				bson.M{"$project": bson.M{"name": 1}},
+			},
+			"as": "user",
+		}}},
+		bson.D{{Key: "$project", Value: bson.M{
+			"_id":       0,
+			"user_id":   "$members.user_id",
+			"is_admin":  bson.M{"$ifNull": bson.A{"$members.is_admin", false}},
+			"joined_at": "$members.joined_at",
+			"name":      bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$user.name", 0}}, ""}},
+		}}},
+	)
+	if q != "" {
+		pipeline = append(pipeline,
+			bson.D{{Key: "$match", Value: bson.M{
+				"name": bson.M{"$regex": regexp.QuoteMeta(q), "$options": "i"},
+			}}},
+			bson.D{{Key: "$limit", Value: limit}},
+		)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	members := []MemberEntry{}
+	if err := cursor.All(ctx, &members); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding members"})
+		return
+	}
+
+	var nextCursor string
+	if len(members) == limit {
+		nextCursor = members[len(members)-1].UserID
+	}
+
+	c.JSON(http.StatusOK, gin.H{"members": members, "next_cursor": nextCursor})
+}
@@ -2,37 +2,41 @@ package group
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"time"
 
+	"gochat_server/internal/api/media"
 	"gochat_server/internal/db"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // Create a new group
 func CreateGroup(c *gin.Context) {
-	var newGroup Group
-	if err := c.ShouldBindJSON(&newGroup); err != nil {
+	var request CreateGroupRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
+	creatorID := c.Query("user_id")
+	if creatorID == "" {
+		creatorID = request.CreatedBy
+	}
+	if creatorID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing user_id"})
+		return
+	}
 
-	newGroup.ID = primitive.NewObjectID().Hex()
-	newGroup.CreatedAt = time.Now().Format(time.RFC3339)
-	newGroup.UpdatedAt = newGroup.CreatedAt
-	// Iterate over existing members and update their JoinedAt
-	for i := range newGroup.Members {
-		newGroup.Members[i].JoinedAt = newGroup.CreatedAt // Set all members' JoinedAt to current time
+	newGroup := request.newGroup(creatorID, time.Now().Format(time.RFC3339))
+	if newGroup.GroupIcon != "" {
+		if !ownsImage(newGroup.CreatedBy, newGroup.GroupIcon) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "group_icon must be the ID of an image you uploaded"})
+			return
+		}
+		media.MakePublic(newGroup.GroupIcon)
 	}
-	// Add the creator separately
-	newGroup.Members = append(newGroup.Members, GroupMember{
-		UserID:   newGroup.CreatedBy,
-		IsAdmin:  true,
-		JoinedAt: newGroup.CreatedAt,
-	})
 	GroupCollection := db.GetCollection("groups")
 	_, err := GroupCollection.InsertOne(context.TODO(), newGroup)
 	if err != nil {
@@ -43,23 +47,53 @@ func CreateGroup(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{"message": "Group created successfully", "group": newGroup})
 }
 
-// Join an existing group
+// Add someone else to an existing group. Adding others takes an admin unless
+// the group lets members add; joining yourself goes through an invite link.
 func JoinGroup(c *gin.Context) {
 	groupID := c.Param("id")
+	userID := c.Query("user_id")
 	var member GroupMember
 
-	if err := c.ShouldBindJSON(&member); err != nil {
+	if err := c.ShouldBindJSON(&member); err != nil || member.UserID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
-	GroupCollection := db.GetCollection("groups")
-	update := bson.M{"$push": bson.M{"members": member}, "$set": bson.M{"updated_at": time.Now().Format(time.RFC3339)}}
-	_, err := GroupCollection.UpdateOne(context.TODO(), bson.M{"_id": groupID}, update)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing user_id"})
+		return
+	}
 
+	group, err := FindGroup(groupID)
 	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+	if _, ok := group.GetMember(member.UserID); ok {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is already a member of the group"})
+		return
+	}
+
+	if userID == member.UserID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Joining a group requires an invite link"})
+		return
+	}
+	if _, ok := group.GetMember(userID); !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only group members can add members"})
+		return
+	}
+	if !group.IsAdmin(userID) {
+		if !group.MemberCanAdd {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can add members to this group"})
+			return
+		}
+		member.IsAdmin = false
+	}
+
+	if err := addMember(group, member); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join group"})
 		return
 	}
+	emitSystemMessage(groupID, SystemEvent{Action: ActionMemberAdded, ActorID: userID, TargetID: member.UserID})
 
 	c.JSON(http.StatusOK, gin.H{"message": "User added to group"})
 }
@@ -81,9 +115,12 @@ func DeleteGroup(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Only the creator can delete the group"})
 		return
 	}
+	if group.IsAnnouncement {
+		c.JSON(http.StatusConflict, gin.H{"error": "A community's announcement group can't be deleted"})
+		return
+	}
 
-	_, err = GroupCollection.DeleteOne(context.TODO(), bson.M{"_id": groupID})
-	if err != nil {
+	if err := deleteGroup(group); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete group"})
 		return
 	}
@@ -91,63 +128,98 @@ func DeleteGroup(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Group deleted successfully"})
 }
 
-// Update group details (Only Admins can update)
+// Update group details. Admins can change anything; members can change the
+// title, description and icon when the group allows it. Every change is
+// recorded in the group's audit log.
 func UpdateGroup(c *gin.Context) {
 	groupID := c.Param("id")
 	userID := c.Query("user_id")
 
-	var updates map[string]interface{}
-	if err := c.ShouldBindJSON(&updates); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+	var request UpdateGroupRequest
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
 		return
 	}
 	GroupCollection := db.GetCollection("groups")
-	// Check if the user is an admin
-	var group Group
-	err := GroupCollection.FindOne(context.TODO(), bson.M{"_id": groupID}).Decode(&group)
+	group, err := FindGroup(groupID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
 		return
 	}
 
-	isAdmin := false
-	for _, member := range group.Members {
-		if member.UserID == userID && member.IsAdmin {
-			isAdmin = true
-			break
-		}
+	if _, ok := group.GetMember(userID); !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only group members can update group details"})
+		return
 	}
 
-	if !isAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can update group details"})
+	changes, err := request.changes(group, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(changes) == 0 {
+		c.JSON(http.StatusOK, gin.H{"message": "Nothing to update"})
 		return
 	}
 
-	updates["updated_at"] = time.Now().Format(time.RFC3339)
+	if !group.IsAdmin(userID) {
+		if !group.MemberCanEdit {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can edit group info"})
+			return
+		}
+		for _, change := range changes {
+			if !memberEditableFields[change.Field] {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can change " + change.Field})
+				return
+			}
+		}
+	}
+
+	updates := bson.M{"updated_at": time.Now().Format(time.RFC3339)}
+	for _, change := range changes {
+		updates[change.Field] = change.New
+	}
 	_, err = GroupCollection.UpdateOne(context.TODO(), bson.M{"_id": groupID}, bson.M{"$set": updates})
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update group"})
 		return
 	}
+	if request.GroupIcon != nil && *request.GroupIcon != "" {
+		// Icons show up in invite previews, outside the group
+		media.MakePublic(*request.GroupIcon)
+	}
+	writeAuditEntry(groupID, userID, changes)
+	emitSettingsChanges(groupID, userID, changes)
 
-	c.JSON(http.StatusOK, gin.H{"message": "Group updated successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Group updated successfully", "changes": changes})
 }
 
-// Leave a group
+// Leave a group. When the last admin or the owner leaves, the longest-standing
+// remaining member takes over; when the last member leaves the group is deleted.
 func LeaveGroup(c *gin.Context) {
 	groupID := c.Param("id")
 	userID := c.Query("user_id")
-	GroupCollection := db.GetCollection("groups")
-	update := bson.M{"$pull": bson.M{"members": bson.M{"user_id": userID}}, "$set": bson.M{"updated_at": time.Now().Format(time.RFC3339)}}
-	_, err := GroupCollection.UpdateOne(context.TODO(), bson.M{"_id": groupID}, update)
 
+	group, err := FindGroup(groupID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+	if _, ok := group.GetMember(userID); !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User is not a member of the group"})
+		return
+	}
+
+	deleted, err := leaveGroup(group, userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave group"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "User left the group"})
+	c.JSON(http.StatusOK, gin.H{"message": "User left the group", "group_deleted": deleted})
 }
 
 // Get Group Data
@@ -163,3 +235,32 @@ func GetGroupData(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"group": group})
 }
+
+// Mute or unmute a group for the calling member
+func MuteGroup(c *gin.Context) {
+	groupID := c.Param("id")
+	userID := c.Query("user_id")
+
+	var request MuteGroupRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	GroupCollection := db.GetCollection("groups")
+	result, err := GroupCollection.UpdateOne(
+		context.TODO(),
+		bson.M{"_id": groupID, "members.user_id": userID},
+		bson.M{"$set": bson.M{"members.$.muted": request.Muted}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update group"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found or not a member"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Group mute updated", "muted": request.Muted})
+}
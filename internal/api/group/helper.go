@@ -0,0 +1,255 @@
+package group
+
+import (
+	"context"
+	"time"
+
+	"gochat_server/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memberEditableFields are the group info fields non-admins may change when
+// MemberCanEdit is set
+var memberEditableFields = map[string]bool{
+	"title":       true,
+	"description": true,
+	"group_icon":  true,
+}
+
+// FindGroup loads a group by its ID
+func FindGroup(groupID string) (Group, error) {
+	var group Group
+	err := db.GetCollection("groups").FindOne(context.TODO(), bson.M{"_id": groupID}).Decode(&group)
+	return group, err
+}
+
+// GetMember returns the membership entry for userID, if they are in the group
+func (g Group) GetMember(userID string) (GroupMember, bool) {
+	for _, member := range g.Members {
+		if member.UserID == userID {
+			return member, true
+		}
+	}
+	return GroupMember{}, false
+}
+
+// IsAdmin reports whether userID is an admin of the group
+func (g Group) IsAdmin(userID string) bool {
+	member, ok := g.GetMember(userID)
+	return ok && member.IsAdmin
+}
+
+// adminCount returns how many members are admins
+func (g Group) adminCount() int {
+	count := 0
+	for _, member := range g.Members {
+		if member.IsAdmin {
+			count++
+		}
+	}
+	return count
+}
+
+// successor picks who takes over when leaving goes: the longest-standing
+// remaining admin, or failing that the longest-standing remaining member.
+// Members are stored in the order they joined.
+func (g Group) successor(leaving string) (GroupMember, bool) {
+	var oldest *GroupMember
+	for i := range g.Members {
+		member := &g.Members[i]
+		if member.UserID == leaving {
+			continue
+		}
+		if member.IsAdmin {
+			return *member, true
+		}
+		if oldest == nil {
+			oldest = member
+		}
+	}
+	if oldest == nil {
+		return GroupMember{}, false
+	}
+	return *oldest, true
+}
+
+// newGroup builds the group creatorID asked for. The creator is its admin;
+// it belongs to no community until linked through the community endpoints.
+func (r CreateGroupRequest) newGroup(creatorID, now string) Group {
+	group := Group{
+		ID:              primitive.NewObjectID().Hex(),
+		Title:           r.Title,
+		Description:     r.Description,
+		GroupIcon:       r.GroupIcon,
+		CreatedBy:       creatorID,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		DisappearingMsg: r.DisappearingMsg,
+		// Members can send and edit the group info unless the creator says otherwise
+		MemberCanEdit: r.MemberCanEdit == nil || *r.MemberCanEdit,
+		MemberCanSend: r.MemberCanSend == nil || *r.MemberCanSend,
+		MemberCanAdd:  r.MemberCanAdd,
+		AdminApprove:  r.AdminApprove,
+		MemberCanPin:  r.MemberCanPin,
+	}
+	for _, member := range r.Members {
+		if member.UserID == "" || member.UserID == creatorID {
+			continue
+		}
+		group.Members = append(group.Members, GroupMember{UserID: member.UserID, IsAdmin: member.IsAdmin, JoinedAt: now})
+	}
+	group.Members = append(group.Members, GroupMember{UserID: creatorID, IsAdmin: true, JoinedAt: now})
+	return group
+}
+
+// addMember appends member to the group. The members.user_id guard keeps a
+// concurrent request from adding the same user twice. Members added to a group
+// that belongs to a community also join the community.
+func addMember(group Group, member GroupMember) error {
+	now := time.Now().Format(time.RFC3339)
+	member.JoinedAt = now
+	_, err := db.GetCollection("groups").UpdateOne(
+		context.TODO(),
+		bson.M{"_id": group.ID, "members.user_id": bson.M{"$ne": member.UserID}},
+		bson.M{"$push": bson.M{"members": member}, "$set": bson.M{"updated_at": now}},
+	)
+	if err == nil && group.CommunityID != "" && !group.IsAnnouncement {
+		onCommunityMemberAdded(group.CommunityID, member.UserID)
+	}
+	return err
+}
+
+// memberLeft tells the community a group belongs to that userID has left the
+// group, so they leave the community too once they are in none of its groups
+func memberLeft(group Group, userID string) {
+	if group.CommunityID != "" && !group.IsAnnouncement {
+		onCommunityMemberLeft(group.CommunityID, userID)
+	}
+}
+
+// groupDeleted tells the community a deleted group belonged to that it is gone
+func groupDeleted(group Group) {
+	if group.CommunityID != "" {
+		onCommunityGroupDeleted(group)
+	}
+}
+
+// deleteGroup removes the group and takes it off its community
+func deleteGroup(group Group) error {
+	_, err := db.GetCollection("groups").DeleteOne(context.TODO(), bson.M{"_id": group.ID})
+	if err == nil {
+		groupDeleted(group)
+	}
+	return err
+}
+
+// AddMember adds userID to the group as a regular member on actorID's behalf
+// and tells the group about it
+func AddMember(group Group, userID, actorID string) error {
+	if _, ok := group.GetMember(userID); ok {
+		return nil
+	}
+	if err := addMember(group, GroupMember{UserID: userID}); err != nil {
+		return err
+	}
+	action := ActionMemberAdded
+	if actorID == userID {
+		action = ActionMemberJoined
+	}
+	emitSystemMessage(group.ID, SystemEvent{Action: action, ActorID: actorID, TargetID: userID})
+	return nil
+}
+
+// leaveGroup takes userID out of the group, handing ownership or admin rights
+// to a successor when needed, and deletes the group when nobody is left. A
+// community's announcement group lives as long as the community, so it is
+// kept even when empty.
+func leaveGroup(group Group, userID string) (bool, error) {
+	successor, hasSuccessor := group.successor(userID)
+	if !hasSuccessor && !group.IsAnnouncement {
+		err := deleteGroup(group)
+		return err == nil, err
+	}
+
+	if err := removeMember(group.ID, userID); err != nil {
+		return false, err
+	}
+	emitSystemMessage(group.ID, SystemEvent{Action: ActionMemberLeft, ActorID: userID})
+	memberLeft(group, userID)
+	if !hasSuccessor {
+		return false, nil
+	}
+
+	member, _ := group.GetMember(userID)
+	if group.CreatedBy == userID {
+		if err := transferOwnership(group.ID, successor.UserID); err != nil {
+			return false, err
+		}
+		emitSystemMessage(group.ID, SystemEvent{Action: ActionOwnerChanged, ActorID: userID, TargetID: successor.UserID})
+	} else if member.IsAdmin && group.adminCount() == 1 {
+		if err := setAdmin(group.ID, successor.UserID, true); err != nil {
+			return false, err
+		}
+		emitSystemMessage(group.ID, SystemEvent{Action: ActionAdminPromoted, ActorID: userID, TargetID: successor.UserID})
+	}
+	return false, nil
+}
+
+// Leave takes userID out of the group the same way LeaveGroup does
+func Leave(groupID, userID string) error {
+	group, err := FindGroup(groupID)
+	if err != nil {
+		return err
+	}
+	if _, ok := group.GetMember(userID); !ok {
+		return nil
+	}
+	_, err = leaveGroup(group, userID)
+	return err
+}
+
+// CreateAnnouncementGroup creates the announcement group of a community, where
+// only admins can post
+func CreateAnnouncementGroup(title, createdBy, communityID string) (Group, error) {
+	now := time.Now().Format(time.RFC3339)
+	group := Group{
+		ID:             primitive.NewObjectID().Hex(),
+		Title:          title,
+		CreatedBy:      createdBy,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		CommunityID:    communityID,
+		IsAnnouncement: true,
+		MemberCanSend:  false,
+		MemberCanEdit:  false,
+		Members:        []GroupMember{{UserID: createdBy, IsAdmin: true, JoinedAt: now}},
+	}
+	_, err := db.GetCollection("groups").InsertOne(context.TODO(), group)
+	return group, err
+}
+
+// Delete removes a group outright, as when the community it was made for
+// couldn't be created
+func Delete(groupID string) error {
+	group, err := FindGroup(groupID)
+	if err != nil {
+		return err
+	}
+	return deleteGroup(group)
+}
+
+// SetCommunity links the group to a community, or unlinks it when
+// communityID is empty
+func SetCommunity(groupID, communityID string) error {
+	update := bson.M{"$set": bson.M{"community_id": communityID, "updated_at": time.Now().Format(time.RFC3339)}}
+	if communityID == "" {
+		update = bson.M{
+			"$unset": bson.M{"community_id": ""},
+			"$set":   bson.M{"updated_at": time.Now().Format(time.RFC3339)},
+		}
+	}
+	_, err := db.GetCollection("groups").UpdateOne(context.TODO(), bson.M{"_id": groupID}, update)
+	return err
+}
@@ -0,0 +1,116 @@
+package group
+
+import "testing"
+
+func TestSuccessor(t *testing.T) {
+	tests := []struct {
+		name    string
+		members []GroupMember
+		leaving string
+		want    string
+		ok      bool
+	}{
+		{
+			name:    "oldest remaining admin",
+			members: []GroupMember{{UserID: "owner", IsAdmin: true}, {UserID: "m1"}, {UserID: "a1", IsAdmin: true}, {UserID: "a2", IsAdmin: true}},
+			leaving: "owner",
+			want:    "a1",
+			ok:      true,
+		},
+		{
+			name:    "oldest member when no admin remains",
+			members: []GroupMember{{UserID: "owner", IsAdmin: true}, {UserID: "m1"}, {UserID: "m2"}},
+			leaving: "owner",
+			want:    "m1",
+			ok:      true,
+		},
+		{
+			name:    "leaving member is skipped",
+			members: []GroupMember{{UserID: "m1", IsAdmin: true}, {UserID: "m2"}},
+			leaving: "m1",
+			want:    "m2",
+			ok:      true,
+		},
+		{
+			name:    "nobody left",
+			members: []GroupMember{{UserID: "owner", IsAdmin: true}},
+			leaving: "owner",
+		},
+		{
+			name:    "empty group",
+			leaving: "owner",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Group{Members: tt.members}.successor(tt.leaving)
+			if ok != tt.ok || got.UserID != tt.want {
+				t.Errorf("successor(%q) = %q, %v; want %q, %v", tt.leaving, got.UserID, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestNewGroup(t *testing.T) {
+	off := false
+	request := CreateGroupRequest{
+		Title:         "Hikers",
+		MemberCanSend: &off,
+		Members: []GroupMember{
+			{UserID: "m1"},
+			{UserID: "a1", IsAdmin: true, Muted: true},
+			{UserID: ""},
+			{UserID: "creator"},
+		},
+	}
+
+	group := request.newGroup("creator", "2024-01-01T00:00:00Z")
+	if group.CreatedBy != "creator" || group.CommunityID != "" || group.IsAnnouncement {
+		t.Errorf("newGroup() created_by = %q, community_id = %q, is_announcement = %v", group.CreatedBy, group.CommunityID, group.IsAnnouncement)
+	}
+	if !group.MemberCanEdit || group.MemberCanSend {
+		t.Errorf("newGroup() member_can_edit = %v, member_can_send = %v; want true, false", group.MemberCanEdit, group.MemberCanSend)
+	}
+
+	want := []GroupMember{
+		{UserID: "m1", JoinedAt: "2024-01-01T00:00:00Z"},
+		{UserID: "a1", IsAdmin: true, JoinedAt: "2024-01-01T00:00:00Z"},
+		{UserID: "creator", IsAdmin: true, JoinedAt: "2024-01-01T00:00:00Z"},
+	}
+	if len(group.Members) != len(want) {
+		t.Fatalf("newGroup() members = %+v, want %+v", group.Members, want)
+	}
+	for i := range want {
+		if group.Members[i] != want[i] {
+			t.Errorf("newGroup() member %d = %+v, want %+v", i, group.Members[i], want[i])
+		}
+	}
+}
+
+func TestCommunityHooks(t *testing.T) {
+	originalLeft, originalDeleted := onCommunityMemberLeft, onCommunityGroupDeleted
+	t.Cleanup(func() { onCommunityMemberLeft, onCommunityGroupDeleted = originalLeft, originalDeleted })
+
+	var left, deleted []string
+	onCommunityMemberLeft = func(communityID, userID string) { left = append(left, communityID+"/"+userID) }
+	onCommunityGroupDeleted = func(group Group) { deleted = append(deleted, group.CommunityID+"/"+group.ID) }
+
+	linked := Group{ID: "g1", CommunityID: "c1"}
+	announcements := Group{ID: "g2", CommunityID: "c1", IsAnnouncement: true}
+	standalone := Group{ID: "g3"}
+
+	for _, group := range []Group{linked, announcements, standalone} {
+		memberLeft(group, "u1")
+		groupDeleted(group)
+	}
+
+	// Leaving the announcement group is how someone leaves the community, so
+	// it doesn't cascade back
+	if len(left) != 1 || left[0] != "c1/u1" {
+		t.Errorf("member left hook calls = %v, want [c1/u1]", left)
+	}
+	if len(deleted) != 2 || deleted[0] != "c1/g1" || deleted[1] != "c1/g2" {
+		t.Errorf("group deleted hook calls = %v, want [c1/g1 c1/g2]", deleted)
+	}
+}
@@ -0,0 +1,75 @@
+package group
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gochat_server/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureIndexes creates the indexes the group collections rely on
+func EnsureIndexes() {
+	indexes := map[string][]mongo.IndexModel{
+		"groups": {
+			{Keys: bson.D{{Key: "members.user_id", Value: 1}}},
+			{
+				Keys: bson.D{{Key: "invite_code", Value: 1}},
+				Options: options.Index().
+					SetUnique(true).
+					SetPartialFilterExpression(bson.M{"invite_code": bson.M{"$exists": true}}),
+			},
+		},
+		"group_audit_log": {
+			{Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+		},
+		"group_join_requests": {
+			{Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: 1}}},
+		},
+	}
+
+	for collection, models := range indexes {
+		if _, err := db.GetCollection(collection).Indexes().CreateMany(context.TODO(), models); err != nil {
+			fmt.Printf("Failed to create indexes on %s: %v\n", collection, err)
+		}
+	}
+}
+
+// defaultsMigration names the MigrateDefaults run in the migrations collection
+const defaultsMigration = "group_member_permissions"
+
+// MigrateDefaults stores the member send and edit permissions on groups
+// created before they were stored explicitly. Those groups let members do
+// both; announcement groups stay admin-only. It runs once, on the first start
+// of a server that stores the permissions, so every group missing them then
+// predates them; the run is recorded in the migrations collection.
+func MigrateDefaults() {
+	migrations := db.GetCollection("migrations")
+	if count, err := migrations.CountDocuments(context.TODO(), bson.M{"_id": defaultsMigration}); err != nil || count > 0 {
+		if err != nil {
+			fmt.Println("Failed to look up group migrations:", err)
+		}
+		return
+	}
+
+	for _, field := range []string{"member_can_send", "member_can_edit"} {
+		_, err := db.GetCollection("groups").UpdateMany(
+			context.TODO(),
+			bson.M{field: bson.M{"$exists": false}, "is_announcement": bson.M{"$ne": true}},
+			bson.M{"$set": bson.M{field: true}},
+		)
+		if err != nil {
+			fmt.Printf("Failed to migrate %s on groups: %v\n", field, err)
+			return
+		}
+	}
+
+	_, err := migrations.InsertOne(context.TODO(), bson.M{"_id": defaultsMigration, "ran_at": time.Now().UTC().Format(time.RFC3339)})
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		fmt.Println("Failed to record group migration:", err)
+	}
+}
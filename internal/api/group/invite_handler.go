@@ -0,0 +1,163 @@
+package group
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"gochat_server/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Get the group's current invite code (admins only)
+func GetInvite(c *gin.Context) {
+	group, ok := loadGroupAsAdmin(c)
+	if !ok {
+		return
+	}
+	if group.InviteCode == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group has no invite link"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invite_code": group.InviteCode})
+}
+
+// Generate an invite code, replacing any existing one (admins only)
+func RotateInvite(c *gin.Context) {
+	group, ok := loadGroupAsAdmin(c)
+	if !ok {
+		return
+	}
+
+	code, err := newInviteCode()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate invite code"})
+		return
+	}
+
+	_, err = db.GetCollection("groups").UpdateOne(
+		context.TODO(),
+		bson.M{"_id": group.ID},
+		bson.M{"$set": bson.M{"invite_code": code, "updated_at": time.Now().Format(time.RFC3339)}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update invite code"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invite_code": code})
+}
+
+// Revoke the group's invite code (admins only)
+func RevokeInvite(c *gin.Context) {
+	group, ok := loadGroupAsAdmin(c)
+	if !ok {
+		return
+	}
+
+	_, err := db.GetCollection("groups").UpdateOne(
+		context.TODO(),
+		bson.M{"_id": group.ID},
+		bson.M{
+			"$unset": bson.M{"invite_code": ""},
+			"$set":   bson.M{"updated_at": time.Now().Format(time.RFC3339)},
+		},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invite code"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invite link revoked"})
+}
+
+// Preview the group behind an invite code without joining it
+func PreviewInvite(c *gin.Context) {
+	group, err := findGroupByInvite(c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invite link is invalid or has been revoked"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"group": InvitePreview{
+		ID:           group.ID,
+		Title:        group.Title,
+		Description:  group.Description,
+		GroupIcon:    group.GroupIcon,
+		MemberCount:  len(group.Members),
+		AdminApprove: group.AdminApprove,
+	}})
+}
+
+// Join the group behind an invite code, or ask to join when the group
+// requires admin approval
+func JoinByInvite(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing user_id"})
+		return
+	}
+
+	group, err := findGroupByInvite(c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invite link is invalid or has been revoked"})
+		return
+	}
+	if _, ok := group.GetMember(userID); ok {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is already a member of the group"})
+		return
+	}
+
+	if group.AdminApprove {
+		request, status, errMsg := createJoinRequest(group, userID)
+		if errMsg != "" {
+			c.JSON(status, gin.H{"error": errMsg})
+			return
+		}
+		c.JSON(status, gin.H{"message": "Join request sent to group admins", "join_request": request})
+		return
+	}
+
+	if err := addMember(group, GroupMember{UserID: userID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join group"})
+		return
+	}
+	emitSystemMessage(group.ID, SystemEvent{Action: ActionMemberJoined, ActorID: userID, TargetID: userID})
+
+	c.JSON(http.StatusOK, gin.H{"message": "User added to group", "group_id": group.ID})
+}
+
+// loadGroupAsAdmin loads the :id group and checks the caller is one of its
+// admins, writing the error response when not
+func loadGroupAsAdmin(c *gin.Context) (Group, bool) {
+	group, err := FindGroup(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return Group{}, false
+	}
+	if !group.IsAdmin(c.Query("user_id")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can manage invite links"})
+		return Group{}, false
+	}
+	return group, true
+}
+
+func findGroupByInvite(code string) (Group, error) {
+	var group Group
+	err := db.GetCollection("groups").FindOne(context.TODO(), bson.M{"invite_code": code}).Decode(&group)
+	return group, err
+}
+
+// newInviteCode returns a random, URL-safe invite code
+func newInviteCode() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
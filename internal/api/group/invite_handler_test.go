@@ -0,0 +1,26 @@
+package group
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestNewInviteCode(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		code, err := newInviteCode()
+		if err != nil {
+			t.Fatalf("newInviteCode() error: %v", err)
+		}
+		if len(code) != 22 {
+			t.Errorf("newInviteCode() = %q, want 22 characters", code)
+		}
+		if url.PathEscape(code) != code {
+			t.Errorf("newInviteCode() = %q, not URL safe", code)
+		}
+		if seen[code] {
+			t.Fatalf("newInviteCode() repeated %q", code)
+		}
+		seen[code] = true
+	}
+}
@@ -0,0 +1,178 @@
+package group
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"gochat_server/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	joinRequestPending   = "pending"
+	joinRequestApproved  = "approved"
+	joinRequestRejected  = "rejected"
+	joinRequestCancelled = "cancelled"
+)
+
+// createJoinRequest files a pending request for userID to join group and
+// tells the group's admins about it
+func createJoinRequest(group Group, userID string) (JoinRequest, int, string) {
+	collection := db.GetCollection("group_join_requests")
+
+	count, err := collection.CountDocuments(context.TODO(), bson.M{
+		"group_id": group.ID,
+		"user_id":  userID,
+		"status":   joinRequestPending,
+	})
+	if err != nil {
+		return JoinRequest{}, http.StatusInternalServerError, "Failed to create join request"
+	}
+	if count > 0 {
+		return JoinRequest{}, http.StatusConflict, "A join request is already pending"
+	}
+
+	request := JoinRequest{
+		ID:        primitive.NewObjectID().Hex(),
+		GroupID:   group.ID,
+		UserID:    userID,
+		Status:    joinRequestPending,
+		CreatedAt: time.Now().Format(time.RFC3339),
+	}
+	if _, err := collection.InsertOne(context.TODO(), request); err != nil {
+		return JoinRequest{}, http.StatusInternalServerError, "Failed to create join request"
+	}
+
+	notifyAdmins(group, "group_join_request", request)
+	return request, http.StatusAccepted, ""
+}
+
+// List the pending join requests of a group (admins only)
+func GetJoinRequests(c *gin.Context) {
+	groupID := c.Param("id")
+	userID := c.Query("user_id")
+
+	group, err := FindGroup(groupID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+	if !group.IsAdmin(userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can view join requests"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := db.GetCollection("group_join_requests").Find(ctx, bson.M{"group_id": groupID, "status": joinRequestPending}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch join requests"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	requests := []JoinRequest{}
+	if err := cursor.All(ctx, &requests); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding join requests"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"join_requests": requests})
+}
+
+// Approve a join request, adding the requester to the group (admins only)
+func ApproveJoinRequest(c *gin.Context) {
+	resolveJoinRequest(c, joinRequestApproved)
+}
+
+// Reject a join request (admins only)
+func RejectJoinRequest(c *gin.Context) {
+	resolveJoinRequest(c, joinRequestRejected)
+}
+
+func resolveJoinRequest(c *gin.Context, status string) {
+	requestID := c.Param("id")
+	userID := c.Query("user_id")
+
+	collection := db.GetCollection("group_join_requests")
+	var request JoinRequest
+	if err := collection.FindOne(context.TODO(), bson.M{"_id": requestID}).Decode(&request); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Join request not found"})
+		return
+	}
+
+	group, err := FindGroup(request.GroupID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+	if !group.IsAdmin(userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can resolve join requests"})
+		return
+	}
+
+	// Claim the request first so two admins can't both act on it
+	err = collection.FindOneAndUpdate(
+		context.TODO(),
+		bson.M{"_id": requestID, "status": joinRequestPending},
+		bson.M{"$set": bson.M{
+			"status":      status,
+			"resolved_at": time.Now().Format(time.RFC3339),
+			"resolved_by": userID,
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&request)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusConflict, gin.H{"error": "Join request is no longer pending"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update join request"})
+		return
+	}
+
+	if status == joinRequestApproved {
+		if err := addMember(group, GroupMember{UserID: request.UserID}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add member"})
+			return
+		}
+		emitSystemMessage(group.ID, SystemEvent{Action: ActionMemberAdded, ActorID: userID, TargetID: request.UserID})
+	}
+
+	notify(request.UserID, map[string]interface{}{
+		"type": "group_join_request_result",
+		"data": request,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Join request " + status, "join_request": request})
+}
+
+// Cancel your own pending join request
+func CancelJoinRequest(c *gin.Context) {
+	requestID := c.Param("id")
+	userID := c.Query("user_id")
+
+	result, err := db.GetCollection("group_join_requests").UpdateOne(
+		context.TODO(),
+		bson.M{"_id": requestID, "user_id": userID, "status": joinRequestPending},
+		bson.M{"$set": bson.M{"status": joinRequestCancelled, "resolved_at": time.Now().Format(time.RFC3339)}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel join request"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No pending join request found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Join request cancelled"})
+}
@@ -4,6 +4,11 @@ type GroupMember struct {
 	UserID   string `bson:"user_id,omitempty" json:"user_id"`
 	IsAdmin  bool   `bson:"is_admin,omitempty" json:"is_admin"`
 	JoinedAt string `bson:"joined_at,omitempty" json:"joined_at"` // Stored as string
+	Muted    bool   `bson:"muted,omitempty" json:"muted"`
+}
+
+type MuteGroupRequest struct {
+	Muted bool `json:"muted"`
 }
 
 type Group struct {
@@ -15,9 +20,103 @@ type Group struct {
 	CreatedAt   string        `bson:"created_at,omitempty" json:"created_at"`   // Stored as string
 	UpdatedAt   string        `bson:"updated_at,omitempty" json:"updated_at"`   // Stored as string
 	DisappearingMsg int       `bson:"disappearing_msg,omitempty" json:"disappearing_msg"`
-	MemberCanEdit bool 		  `bson:"member_can_edit,omitempty" json:"member_can_edit"`
-	MemberCanSend bool 		  `bson:"member_can_send,omitempty" json:"member_can_send"`
+	MemberCanEdit bool 		  `bson:"member_can_edit" json:"member_can_edit"`
+	MemberCanSend bool 		  `bson:"member_can_send" json:"member_can_send"`
 	MemberCanAdd  bool 		  `bson:"member_can_add,omitempty" json:"member_can_add"`
 	AdminApprove  bool 		  `bson:"admin_approve,omitempty" json:"admin_approve"`
+	MemberCanPin  bool 		  `bson:"member_can_pin,omitempty" json:"member_can_pin"`
+	InviteCode    string 	  `bson:"invite_code,omitempty" json:"-"` // Only shown to admins via the invite endpoints
+	CommunityID   string 	  `bson:"community_id,omitempty" json:"community_id,omitempty"`
+	IsAnnouncement bool 	  `bson:"is_announcement,omitempty" json:"is_announcement"` // A community's announcement group
 	Members     []GroupMember `bson:"members,omitempty" json:"members"`
 }
+
+
+// JoinRequest is a pending request to join a group that requires admin approval
+type JoinRequest struct {
+	ID         string `bson:"_id" json:"_id"`
+	GroupID    string `bson:"group_id" json:"group_id"`
+	UserID     string `bson:"user_id" json:"user_id"`
+	Status     string `bson:"status" json:"status"` // pending, approved, rejected or cancelled
+	CreatedAt  string `bson:"created_at" json:"created_at"`
+	ResolvedAt string `bson:"resolved_at,omitempty" json:"resolved_at,omitempty"`
+	ResolvedBy string `bson:"resolved_by,omitempty" json:"resolved_by,omitempty"`
+}
+
+// InvitePreview is what anyone holding an invite code can see before joining
+type InvitePreview struct {
+	ID           string `json:"_id"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	GroupIcon    string `json:"group_icon"`
+	MemberCount  int    `json:"member_count"`
+	AdminApprove bool   `json:"admin_approve"`
+}
+
+// CreateGroupRequest is the body of CreateGroup. A group joins a community
+// only through the community endpoints, so neither that nor who created it
+// can be set here. Member permissions default to on when left out.
+type CreateGroupRequest struct {
+	Title           string        `json:"title"`
+	Description     string        `json:"description"`
+	GroupIcon       string        `json:"group_icon"` // Media ID of an uploaded image
+	CreatedBy       string        `json:"created_by"` // only read when user_id is missing, as older clients sent it
+	DisappearingMsg int           `json:"disappearing_msg"`
+	MemberCanEdit   *bool         `json:"member_can_edit"`
+	MemberCanSend   *bool         `json:"member_can_send"`
+	MemberCanAdd    bool          `json:"member_can_add"`
+	AdminApprove    bool          `json:"admin_approve"`
+	MemberCanPin    bool          `json:"member_can_pin"`
+	Members         []GroupMember `json:"members"`
+}
+
+// UpdateGroupRequest is the patch body for UpdateGroup. Only the fields set in
+// the request are changed; anything not listed here is rejected.
+type UpdateGroupRequest struct {
+	Title           *string `json:"title"`
+	Description     *string `json:"description"`
+	GroupIcon       *string `json:"group_icon"` // Media ID of an uploaded image, or "" to clear
+	DisappearingMsg *int    `json:"disappearing_msg"`
+	MemberCanEdit   *bool   `json:"member_can_edit"`
+	MemberCanSend   *bool   `json:"member_can_send"`
+	MemberCanAdd    *bool   `json:"member_can_add"`
+	AdminApprove    *bool   `json:"admin_approve"`
+	MemberCanPin    *bool   `json:"member_can_pin"`
+}
+
+// FieldChange records one field's value before and after a settings update
+type FieldChange struct {
+	Field string      `bson:"field" json:"field"`
+	Old   interface{} `bson:"old" json:"old"`
+	New   interface{} `bson:"new" json:"new"`
+}
+
+// AuditEntry is one settings update in a group's audit log
+type AuditEntry struct {
+	ID        string        `bson:"_id" json:"_id"`
+	GroupID   string        `bson:"group_id" json:"group_id"`
+	ActorID   string        `bson:"actor_id" json:"actor_id"`
+	Changes   []FieldChange `bson:"changes" json:"changes"`
+	CreatedAt string        `bson:"created_at" json:"created_at"`
+}
+
+// GroupSummary is how a group is listed among the caller's groups
+type GroupSummary struct {
+	ID             string `bson:"_id" json:"_id"`
+	Title          string `bson:"title" json:"title"`
+	GroupIcon      string `bson:"group_icon" json:"group_icon"`
+	UpdatedAt      string `bson:"updated_at" json:"updated_at"`
+	CommunityID    string `bson:"community_id,omitempty" json:"community_id,omitempty"`
+	IsAnnouncement bool   `bson:"is_announcement" json:"is_announcement"`
+	MemberCount    int    `bson:"member_count" json:"member_count"`
+	IsAdmin        bool   `bson:"is_admin" json:"is_admin"`
+	Muted          bool   `bson:"muted" json:"muted"`
+}
+
+// MemberEntry is a group member as listed in the member directory
+type MemberEntry struct {
+	UserID   string `bson:"user_id" json:"user_id"`
+	Name     string `bson:"name" json:"name"`
+	IsAdmin  bool   `bson:"is_admin" json:"is_admin"`
+	JoinedAt string `bson:"joined_at" json:"joined_at"`
+}
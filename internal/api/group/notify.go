@@ -0,0 +1,55 @@
+package group
+
+// Notifier delivers a JSON event to a user over WebSocket, queueing it when
+// they are offline
+type Notifier func(userID string, data interface{}) error
+
+// notify is wired to the WebSocket layer at startup; the group package cannot
+// import it directly because the WebSocket handlers depend on groups
+var notify Notifier = func(string, interface{}) error { return nil }
+
+// SetNotifier sets how group events reach members
+func SetNotifier(n Notifier) {
+	notify = n
+}
+
+// onCommunityMemberAdded is wired to the community package at startup so that
+// joining a community's group also joins the community
+var onCommunityMemberAdded = func(communityID, userID string) {}
+
+// SetCommunityMemberHook sets what happens when someone joins a group that
+// belongs to a community
+func SetCommunityMemberHook(hook func(communityID, userID string)) {
+	onCommunityMemberAdded = hook
+}
+
+// onCommunityMemberLeft is wired to the community package at startup so that
+// leaving or being removed from a community's last group the user was in also
+// takes them out of the community
+var onCommunityMemberLeft = func(communityID, userID string) {}
+
+// SetCommunityMemberLeftHook sets what happens when someone leaves, or is
+// removed from, a group that belongs to a community
+func SetCommunityMemberLeftHook(hook func(communityID, userID string)) {
+	onCommunityMemberLeft = hook
+}
+
+// onCommunityGroupDeleted is wired to the community package at startup so that
+// a deleted group is taken off its community, and its members leave the
+// community when it was their last group there
+var onCommunityGroupDeleted = func(group Group) {}
+
+// SetCommunityGroupDeletedHook sets what happens when a group that belongs to
+// a community is deleted
+func SetCommunityGroupDeletedHook(hook func(group Group)) {
+	onCommunityGroupDeleted = hook
+}
+
+// notifyAdmins sends an event to every admin of the group
+func notifyAdmins(group Group, eventType string, data interface{}) {
+	for _, member := range group.Members {
+		if member.IsAdmin {
+			notify(member.UserID, map[string]interface{}{"type": eventType, "data": data})
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package group
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"gochat_server/internal/api/media"
+)
+
+const (
+	maxTitleLength       = 100
+	maxDescriptionLength = 512
+)
+
+// ownsImage reports whether the media is an image the user uploaded. Icons are
+// made public, so only the uploader's own images are accepted.
+var ownsImage = media.IsOwnImage
+
+// changes validates the patch made by actorID and returns the fields that
+// differ from the group's current settings
+func (r UpdateGroupRequest) changes(group Group, actorID string) ([]FieldChange, error) {
+	var changes []FieldChange
+	addString := func(field string, old string, new *string) {
+		if new != nil && *new != old {
+			changes = append(changes, FieldChange{Field: field, Old: old, New: *new})
+		}
+	}
+	addBool := func(field string, old bool, new *bool) {
+		if new != nil && *new != old {
+			changes = append(changes, FieldChange{Field: field, Old: old, New: *new})
+		}
+	}
+
+	if r.Title != nil {
+		if n := utf8.RuneCountInString(*r.Title); n == 0 || n > maxTitleLength {
+			return nil, fmt.Errorf("title must be between 1 and %d characters", maxTitleLength)
+		}
+	}
+	if r.Description != nil && utf8.RuneCountInString(*r.Description) > maxDescriptionLength {
+		return nil, fmt.Errorf("description must be at most %d characters", maxDescriptionLength)
+	}
+	if r.GroupIcon != nil && *r.GroupIcon != "" && !ownsImage(actorID, *r.GroupIcon) {
+		return nil, errors.New("group_icon must be the ID of an image you uploaded")
+	}
+	if group.IsAnnouncement && r.MemberCanSend != nil && *r.MemberCanSend {
+		return nil, errors.New("only admins can post in a community announcement group")
+	}
+	if r.DisappearingMsg != nil && *r.DisappearingMsg < 0 {
+		return nil, errors.New("disappearing_msg cannot be negative")
+	}
+
+	addString("title", group.Title, r.Title)
+	addString("description", group.Description, r.Description)
+	addString("group_icon", group.GroupIcon, r.GroupIcon)
+	if r.DisappearingMsg != nil && *r.DisappearingMsg != group.DisappearingMsg {
+		changes = append(changes, FieldChange{Field: "disappearing_msg", Old: group.DisappearingMsg, New: *r.DisappearingMsg})
+	}
+	addBool("member_can_edit", group.MemberCanEdit, r.MemberCanEdit)
+	addBool("member_can_send", group.MemberCanSend, r.MemberCanSend)
+	addBool("member_can_add", group.MemberCanAdd, r.MemberCanAdd)
+	addBool("admin_approve", group.AdminApprove, r.AdminApprove)
+	addBool("member_can_pin", group.MemberCanPin, r.MemberCanPin)
+
+	return changes, nil
+}
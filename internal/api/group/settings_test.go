@@ -0,0 +1,65 @@
+package group
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUpdateGroupRequestChanges(t *testing.T) {
+	// Only "own-image" counts as an image the actor uploaded
+	defer func(original func(string, string) bool) { ownsImage = original }(ownsImage)
+	ownsImage = func(userID, mediaID string) bool {
+		return userID == "actor" && mediaID == "own-image"
+	}
+
+	str := func(s string) *string { return &s }
+	boolean := func(b bool) *bool { return &b }
+	num := func(n int) *int { return &n }
+
+	group := Group{Title: "Old", Description: "desc", GroupIcon: "old-icon", MemberCanSend: true}
+	announcement := Group{Title: "News", IsAnnouncement: true}
+
+	tests := []struct {
+		name    string
+		group   Group
+		request UpdateGroupRequest
+		fields  []string
+		err     string
+	}{
+		{name: "nothing set", group: group},
+		{name: "unchanged values", group: group, request: UpdateGroupRequest{Title: str("Old"), MemberCanSend: boolean(true)}},
+		{name: "title and flag", group: group, request: UpdateGroupRequest{Title: str("New"), MemberCanPin: boolean(true)}, fields: []string{"title", "member_can_pin"}},
+		{name: "empty title", group: group, request: UpdateGroupRequest{Title: str("")}, err: "title must be"},
+		{name: "long title", group: group, request: UpdateGroupRequest{Title: str(strings.Repeat("a", maxTitleLength+1))}, err: "title must be"},
+		{name: "long description", group: group, request: UpdateGroupRequest{Description: str(strings.Repeat("a", maxDescriptionLength+1))}, err: "description must be"},
+		{name: "own image icon", group: group, request: UpdateGroupRequest{GroupIcon: str("own-image")}, fields: []string{"group_icon"}},
+		{name: "someone else's icon", group: group, request: UpdateGroupRequest{GroupIcon: str("other-image")}, err: "group_icon must be"},
+		{name: "clear icon", group: group, request: UpdateGroupRequest{GroupIcon: str("")}, fields: []string{"group_icon"}},
+		{name: "negative timer", group: group, request: UpdateGroupRequest{DisappearingMsg: num(-1)}, err: "disappearing_msg"},
+		{name: "announcement opened to members", group: announcement, request: UpdateGroupRequest{MemberCanSend: boolean(true)}, err: "only admins can post"},
+		{name: "announcement renamed", group: announcement, request: UpdateGroupRequest{Title: str("Updates")}, fields: []string{"title"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := tt.request.changes(tt.group, "actor")
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(changes) != len(tt.fields) {
+				t.Fatalf("changes = %+v, want fields %v", changes, tt.fields)
+			}
+			for i, field := range tt.fields {
+				if changes[i].Field != field {
+					t.Errorf("changes[%d].Field = %q, want %q", i, changes[i].Field, field)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package group
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gochat_server/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// System event actions carried by group system messages
+const (
+	ActionMemberAdded         = "member_added"
+	ActionMemberJoined        = "member_joined"
+	ActionMemberLeft          = "member_left"
+	ActionMemberRemoved       = "member_removed"
+	ActionAdminPromoted       = "admin_promoted"
+	ActionAdminDemoted        = "admin_demoted"
+	ActionOwnerChanged        = "owner_changed"
+	ActionTitleChanged        = "title_changed"
+	ActionDescriptionChanged  = "description_changed"
+	ActionIconChanged         = "icon_changed"
+	ActionDisappearingChanged = "disappearing_changed"
+)
+
+// SystemEvent is the structured part of a group system message
+type SystemEvent struct {
+	Action   string `bson:"action" json:"action"`
+	ActorID  string `bson:"actor_id" json:"actor_id"`
+	TargetID string `bson:"target_id,omitempty" json:"target_id,omitempty"`
+	Value    string `bson:"value,omitempty" json:"value,omitempty"`
+}
+
+// SystemMessage is stored in the messages collection alongside normal chat
+// messages (same field names) so it shows up in the group's message stream
+type SystemMessage struct {
+	Id         string      `bson:"_id" json:"_id"`
+	SenderId   string      `bson:"sender_id" json:"sender_id"`
+	ReceiverId string      `bson:"receiver_id" json:"receiver_id"`
+	Content    string      `bson:"content" json:"content"`
+	Timestamp  string      `bson:"timestamp" json:"timestamp"`
+	ServerTS   string      `bson:"server_ts" json:"server_ts"`
+	ChatId     string      `bson:"chat_id" json:"chat_id"`
+	GroupId    string      `bson:"group_id" json:"group_id"`
+	Type       string      `bson:"type" json:"type"`
+	Status     string      `bson:"status" json:"status"`
+	System     SystemEvent `bson:"system" json:"system"`
+}
+
+// emitSystemMessage persists a system message for the group and delivers it
+// to every current member, plus the actor and target when they are no longer
+// in the group (e.g. someone who left or was removed)
+func emitSystemMessage(groupID string, event SystemEvent) {
+	group, err := FindGroup(groupID)
+	if err != nil {
+		fmt.Println("Error loading group for system message:", err)
+		return
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	message := SystemMessage{
+		Id:        primitive.NewObjectID().Hex(),
+		SenderId:  event.ActorID,
+		Content:   describeEvent(event),
+		Timestamp: now,
+		ServerTS:  now,
+		ChatId:    groupID,
+		GroupId:   groupID,
+		Type:      "system",
+		Status:    "sent",
+		System:    event,
+	}
+
+	if _, err := db.GetCollection("messages").InsertOne(context.TODO(), message); err != nil {
+		fmt.Println("Failed to persist system message:", err)
+	}
+
+	recipients := make(map[string]bool)
+	for _, member := range group.Members {
+		recipients[member.UserID] = true
+	}
+	for _, userID := range []string{event.ActorID, event.TargetID} {
+		if userID != "" {
+			recipients[userID] = true
+		}
+	}
+
+	for userID := range recipients {
+		notify(userID, map[string]interface{}{"type": "message", "data": message})
+	}
+}
+
+// settingsActions maps the group info fields members see changes to onto
+// their system event action
+var settingsActions = map[string]string{
+	"title":            ActionTitleChanged,
+	"description":      ActionDescriptionChanged,
+	"group_icon":       ActionIconChanged,
+	"disappearing_msg": ActionDisappearingChanged,
+}
+
+// emitSettingsChanges emits a system message for each group info field an
+// update changed
+func emitSettingsChanges(groupID, actorID string, changes []FieldChange) {
+	for _, change := range changes {
+		if action, ok := settingsActions[change.Field]; ok {
+			emitSystemMessage(groupID, SystemEvent{Action: action, ActorID: actorID, Value: fmt.Sprint(change.New)})
+		}
+	}
+}
+
+// describeEvent renders the human readable text of a system event, such as
+// "Alice added Bob"
+func describeEvent(event SystemEvent) string {
+	names := userNames(event.ActorID, event.TargetID)
+	actor, target := names[event.ActorID], names[event.TargetID]
+
+	switch event.Action {
+	case ActionMemberAdded:
+		return fmt.Sprintf("%s added %s", actor, target)
+	case ActionMemberJoined:
+		return fmt.Sprintf("%s joined", target)
+	case ActionMemberLeft:
+		return fmt.Sprintf("%s left", actor)
+	case ActionMemberRemoved:
+		return fmt.Sprintf("%s removed %s", actor, target)
+	case ActionAdminPromoted:
+		return fmt.Sprintf("%s made %s an admin", actor, target)
+	case ActionAdminDemoted:
+		return fmt.Sprintf("%s dismissed %s as admin", actor, target)
+	case ActionOwnerChanged:
+		return fmt.Sprintf("%s is now the group owner", target)
+	case ActionTitleChanged:
+		return fmt.Sprintf("%s changed the title to %q", actor, event.Value)
+	case ActionDescriptionChanged:
+		return fmt.Sprintf("%s changed the group description", actor)
+	case ActionIconChanged:
+		return fmt.Sprintf("%s changed the group icon", actor)
+	case ActionDisappearingChanged:
+		if event.Value == "0" || event.Value == "" {
+			return fmt.Sprintf("%s turned off disappearing messages", actor)
+		}
+		return fmt.Sprintf("%s set disappearing messages to %s", actor, event.Value)
+	}
+	return ""
+}
+
+// userNames looks up display names, falling back to the user ID
+func userNames(userIDs ...string) map[string]string {
+	names := make(map[string]string)
+	var objectIDs []primitive.ObjectID
+	for _, userID := range userIDs {
+		if userID == "" {
+			continue
+		}
+		names[userID] = userID
+		if objectID, err := primitive.ObjectIDFromHex(userID); err == nil {
+			objectIDs = append(objectIDs, objectID)
+		}
+	}
+	if len(objectIDs) == 0 {
+		return names
+	}
+
+	cursor, err := db.GetCollection("users").Find(context.TODO(), bson.M{"_id": bson.M{"$in": objectIDs}})
+	if err != nil {
+		return names
+	}
+	defer cursor.Close(context.TODO())
+
+	for cursor.Next(context.TODO()) {
+		var user struct {
+			ID   primitive.ObjectID `bson:"_id"`
+			Name string             `bson:"name"`
+		}
+		if err := cursor.Decode(&user); err == nil && user.Name != "" {
+			names[user.ID.Hex()] = user.Name
+		}
+	}
+	return names
+}
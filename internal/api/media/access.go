@@ -0,0 +1,279 @@
+package media
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gochat_server/config"
+	"gochat_server/internal/db"
+	"gochat_server/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxSignedURLTTL bounds the lifetime a caller can ask a signed URL to have
+const maxSignedURLTTL = 24 * time.Hour
+
+var (
+	signingKey     []byte
+	signingKeyOnce sync.Once
+)
+
+// callerID identifies the user behind an upload or storage request: the
+// bearer of a valid Authorization token, otherwise the user_id query
+// parameter the rest of the API uses
+func callerID(c *gin.Context) string {
+	if userID := tokenUserID(c); userID != "" {
+		return userID
+	}
+	return c.Query("user_id")
+}
+
+// tokenUserID returns the user a valid Authorization token was issued to, or
+// "". Tokens issued before they carried the user's ID name their phone number,
+// which is looked up. Downloads are authorized on this alone, since anyone can
+// put any user_id in a query.
+func tokenUserID(c *gin.Context) string {
+	token := c.GetHeader("Authorization")
+	if token == "" {
+		return ""
+	}
+	claims, err := utils.ValidateJWT(token)
+	if err != nil {
+		return ""
+	}
+	subject, _ := claims["user_id"].(string)
+	if subject == "" {
+		return ""
+	}
+	if _, err := primitive.ObjectIDFromHex(subject); err == nil {
+		return subject
+	}
+
+	var user struct {
+		ID string `bson:"_id"`
+	}
+	if err := db.GetCollection("users").FindOne(context.TODO(), bson.M{"phone": subject}).Decode(&user); err != nil {
+		return ""
+	}
+	return user.ID
+}
+
+// authorize checks that the request may download media, either through a
+// valid signed URL or because the token's user can see it
+func authorize(c *gin.Context, media Media) bool {
+	if c.Query("sig") != "" {
+		return verifySignature(media.ID, c.Query("expires"), c.Query("sig"))
+	}
+	return canAccess(tokenUserID(c), media)
+}
+
+// CanAccess reports whether userID may see the media: as its owner, as a
+// participant in a chat it was sent in, or because it is public
+func CanAccess(userID, mediaID string) bool {
+	media, err := findMedia(mediaID)
+	return err == nil && canAccess(userID, media)
+}
+
+func canAccess(userID string, media Media) bool {
+	if media.Public {
+		return true
+	}
+	if userID == "" {
+		return false
+	}
+	if media.OwnerID == userID {
+		return true
+	}
+	for _, sharedWith := range media.SharedUsers {
+		if sharedWith == userID {
+			return true
+		}
+	}
+	if len(media.SharedGroups) > 0 {
+		count, err := db.GetCollection("groups").CountDocuments(context.TODO(), bson.M{
+			"_id":             bson.M{"$in": media.SharedGroups},
+			"members.user_id": userID,
+		})
+		if err == nil && count > 0 {
+			return true
+		}
+	}
+	if media.OwnerID == "" {
+		return sentInChatWith(userID, media.ID)
+	}
+	return false
+}
+
+// sentInChatWith covers files uploaded before media had owners: they are
+// visible to the participants of any chat a message carried them in
+func sentInChatWith(userID, mediaID string) bool {
+	or := bson.A{bson.M{"sender_id": userID}, bson.M{"receiver_id": userID}}
+
+	cursor, err := db.GetCollection("groups").Find(context.TODO(), bson.M{"members.user_id": userID})
+	if err == nil {
+		var groups []struct {
+			ID string `bson:"_id"`
+		}
+		if cursor.All(context.TODO(), &groups) == nil && len(groups) > 0 {
+			groupIDs := make([]string, 0, len(groups))
+			for _, g := range groups {
+				groupIDs = append(groupIDs, g.ID)
+			}
+			or = append(or, bson.M{"group_id": bson.M{"$in": groupIDs}})
+		}
+	}
+
+	count, err := db.GetCollection("messages").CountDocuments(context.TODO(), bson.M{"media_id": mediaID, "$or": or})
+	return err == nil && count > 0
+}
+
+// ShareWithChat lets the participants of a chat see a media sent in it: the
+// group's members for a group chat, otherwise the given users
+func ShareWithChat(mediaID, groupID string, userIDs ...string) error {
+	var update bson.M
+	if groupID != "" {
+		update = bson.M{"$addToSet": bson.M{"shared_groups": groupID}}
+	} else {
+		users := []string{}
+		for _, userID := range userIDs {
+			if userID != "" {
+				users = append(users, userID)
+			}
+		}
+		update = bson.M{"$addToSet": bson.M{"shared_users": bson.M{"$each": users}}}
+	}
+	_, err := db.GetCollection("media").UpdateOne(context.TODO(), bson.M{"_id": mediaID}, update)
+	return err
+}
+
+// IsOwner reports whether userID uploaded the media. Only the owner may make
+// a media public, since that shows it to everyone.
+func IsOwner(userID, mediaID string) bool {
+	media, err := findMedia(mediaID)
+	return err == nil && isOwner(userID, media)
+}
+
+// IsOwnImage reports whether the media is an image userID uploaded, as icons
+// must be
+func IsOwnImage(userID, mediaID string) bool {
+	media, err := findMedia(mediaID)
+	return err == nil && isOwner(userID, media) && media.MediaType == "image"
+}
+
+func isOwner(userID string, media Media) bool {
+	return userID != "" && media.OwnerID == userID
+}
+
+// MakePublic lets anyone download a media, for things shown outside any chat
+// such as profile pictures, group and channel icons or channel posts. Callers
+// check the actor owns it first.
+func MakePublic(mediaID string) error {
+	return makePublic([]string{mediaID})
+}
+
+// makePublic marks media records public, and files from before the media
+// collection through their GridFS metadata
+func makePublic(mediaIDs []string) error {
+	if _, err := db.GetCollection("media").UpdateMany(
+		context.TODO(),
+		bson.M{"_id": bson.M{"$in": mediaIDs}},
+		bson.M{"$set": bson.M{"public": true}},
+	); err != nil {
+		return err
+	}
+
+	fileIDs := bson.A{}
+	for _, id := range mediaIDs {
+		if fileID, err := primitive.ObjectIDFromHex(id); err == nil {
+			fileIDs = append(fileIDs, fileID)
+		}
+	}
+	if len(fileIDs) == 0 {
+		return nil
+	}
+	// Only read back for files without a record, as findMedia prefers the record
+	_, err := db.GetCollection("fs.files").UpdateMany(
+		context.TODO(),
+		bson.M{"_id": bson.M{"$in": fileIDs}},
+		bson.M{"$set": bson.M{"metadata.public": true}},
+	)
+	return err
+}
+
+// PublishPictures makes public every media in use as a profile picture or a
+// group, channel or community icon. Pictures set before downloads were
+// restricted, and legacy files which never had a record, stay visible.
+func PublishPictures() {
+	icons, err := iconReferences()
+	if err != nil {
+		fmt.Println("Failed to collect pictures to publish:", err)
+		return
+	}
+	ids := make([]string, 0, gcBatchSize)
+	for id := range icons {
+		ids = append(ids, id)
+		if len(ids) == gcBatchSize {
+			if err := makePublic(ids); err != nil {
+				fmt.Println("Failed to publish pictures:", err)
+			}
+			ids = ids[:0]
+		}
+	}
+	if len(ids) > 0 {
+		if err := makePublic(ids); err != nil {
+			fmt.Println("Failed to publish pictures:", err)
+		}
+	}
+}
+
+// signURL appends an expiry and signature for mediaID to path, making it
+// work without any other credentials until expiresAt
+func signURL(path, mediaID string, expiresAt time.Time) string {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	return fmt.Sprintf("%s%sexpires=%s&sig=%s", path, separator, expires, signature(mediaID, expires))
+}
+
+func verifySignature(mediaID, expires, sig string) bool {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(signature(mediaID, expires)))
+}
+
+func signature(mediaID, expires string) string {
+	mac := hmac.New(sha256.New, urlSigningKey())
+	mac.Write([]byte(mediaID + ":" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// urlSigningKey returns the configured signing key, or a random one when none
+// is set (signed URLs then only work on the instance that issued them)
+func urlSigningKey() []byte {
+	signingKeyOnce.Do(func() {
+		if config.Cfg.MediaURLSecret != "" {
+			signingKey = []byte(config.Cfg.MediaURLSecret)
+			return
+		}
+		log.Println("MEDIA_URL_SECRET is not set; signed media URLs won't work across instances or restarts")
+		signingKey = make([]byte, 32)
+		rand.Read(signingKey)
+	})
+	return signingKey
+}
@@ -0,0 +1,77 @@
+package media
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"gochat_server/config"
+)
+
+func TestSignedURL(t *testing.T) {
+	config.Cfg.MediaURLSecret = "test-secret"
+
+	signed := signURL("/media/m1", "m1", time.Now().Add(time.Minute))
+	parsed, err := url.Parse(signed)
+	if err != nil {
+		t.Fatalf("signURL returned an invalid URL %q: %v", signed, err)
+	}
+	expires, sig := parsed.Query().Get("expires"), parsed.Query().Get("sig")
+
+	expired := signURL("/media/m1", "m1", time.Now().Add(-time.Minute))
+	expiredQuery, _ := url.Parse(expired)
+
+	tests := []struct {
+		name    string
+		mediaID string
+		expires string
+		sig     string
+		want    bool
+	}{
+		{"valid", "m1", expires, sig, true},
+		{"other media", "m2", expires, sig, false},
+		{"extended expiry", "m1", expires + "0", sig, false},
+		{"tampered signature", "m1", expires, strings.Repeat("0", len(sig)), false},
+		{"missing signature", "m1", expires, "", false},
+		{"malformed expiry", "m1", "soon", sig, false},
+		{"expired", "m1", expiredQuery.Query().Get("expires"), expiredQuery.Query().Get("sig"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifySignature(tt.mediaID, tt.expires, tt.sig); got != tt.want {
+				t.Errorf("verifySignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := signURL("/media/m1?original=true", "m1", time.Now()); !strings.HasPrefix(got, "/media/m1?original=true&expires=") {
+		t.Errorf("signURL kept the existing query badly: %q", got)
+	}
+}
+
+func TestOwnership(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID string
+		media  Media
+		owner  bool
+		access bool
+	}{
+		{"owner", "alice", Media{OwnerID: "alice"}, true, true},
+		{"stranger", "bob", Media{OwnerID: "alice"}, false, false},
+		{"shared with", "bob", Media{OwnerID: "alice", SharedUsers: []string{"bob"}}, false, true},
+		{"public", "bob", Media{OwnerID: "alice", Public: true}, false, true},
+		{"anonymous", "", Media{OwnerID: "alice"}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isOwner(tt.userID, tt.media); got != tt.owner {
+				t.Errorf("isOwner() = %v, want %v", got, tt.owner)
+			}
+			if got := canAccess(tt.userID, tt.media); got != tt.access {
+				t.Errorf("canAccess() = %v, want %v", got, tt.access)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"gochat_server/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// blobReader reads a GridFS file straight from its chunks. Unlike the GridFS
+// download stream it can seek: a seek only moves the offset, and the next
+// read starts fetching at the chunk holding it, so serving a range never
+// reads the chunks before it.
+type blobReader struct {
+	ctx       context.Context
+	filesID   primitive.ObjectID
+	length    int64
+	chunkSize int64
+	offset    int64
+
+	cursor    *mongo.Cursor
+	nextChunk int64 // index of the chunk the cursor returns next
+
+	chunk      []byte
+	chunkStart int64 // file offset of chunk[0]
+}
+
+type chunkDoc struct {
+	N    int64  `bson:"n"`
+	Data []byte `bson:"data"`
+}
+
+// openBlobReader opens the GridFS file blobID for reading
+func openBlobReader(ctx context.Context, blobID string) (*blobReader, error) {
+	filesID, err := primitive.ObjectIDFromHex(blobID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	var file struct {
+		Length    int64 `bson:"length"`
+		ChunkSize int64 `bson:"chunkSize"`
+	}
+	err = db.GetCollection("fs.files").FindOne(ctx, bson.M{"_id": filesID}).Decode(&file)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &blobReader{ctx: ctx, filesID: filesID, length: file.Length, chunkSize: file.ChunkSize}, nil
+}
+
+func (r *blobReader) Read(p []byte) (int, error) {
+	if r.offset >= r.length {
+		return 0, io.EOF
+	}
+	if r.offset < r.chunkStart || r.offset >= r.chunkStart+int64(len(r.chunk)) {
+		if err := r.loadChunk(r.offset / r.chunkSize); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.chunk[r.offset-r.chunkStart:])
+	r.offset += int64(n)
+	return n, nil
+}
+
+func (r *blobReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.length
+	default:
+		return 0, errors.New("blobReader.Seek: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("blobReader.Seek: negative position")
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+func (r *blobReader) Close() error {
+	if r.cursor == nil {
+		return nil
+	}
+	return r.cursor.Close(r.ctx)
+}
+
+// loadChunk makes chunk n the current chunk, reusing the open cursor when
+// reads are sequential and starting a new one from n otherwise
+func (r *blobReader) loadChunk(n int64) error {
+	if r.cursor == nil || r.nextChunk != n {
+		r.Close()
+		cursor, err := db.GetCollection("fs.chunks").Find(
+			r.ctx,
+			bson.M{"files_id": r.filesID, "n": bson.M{"$gte": n}},
+			options.Find().SetSort(bson.D{{Key: "n", Value: 1}}),
+		)
+		if err != nil {
+			return err
+		}
+		r.cursor = cursor
+		r.nextChunk = n
+	}
+
+	if !r.cursor.Next(r.ctx) {
+		if err := r.cursor.Err(); err != nil {
+			return err
+		}
+		return io.ErrUnexpectedEOF
+	}
+	var chunk chunkDoc
+	if err := r.cursor.Decode(&chunk); err != nil {
+		return err
+	}
+	if chunk.N != n || len(chunk.Data) == 0 {
+		return io.ErrUnexpectedEOF
+	}
+
+	r.chunk = chunk.Data
+	r.chunkStart = n * r.chunkSize
+	r.nextChunk = n + 1
+	return nil
+}
@@ -0,0 +1,79 @@
+package media
+
+import (
+	"io"
+	"testing"
+)
+
+func TestBlobReaderSeek(t *testing.T) {
+	tests := []struct {
+		name    string
+		start   int64
+		offset  int64
+		whence  int
+		want    int64
+		wantErr bool
+	}{
+		{"from start", 10, 40, io.SeekStart, 40, false},
+		{"from current", 10, 5, io.SeekCurrent, 15, false},
+		{"back from current", 10, -10, io.SeekCurrent, 0, false},
+		{"from end", 10, -20, io.SeekEnd, 80, false},
+		{"past the end", 10, 50, io.SeekEnd, 150, false},
+		{"before the start", 10, -11, io.SeekCurrent, 10, true},
+		{"bad whence", 10, 0, 7, 10, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &blobReader{length: 100, chunkSize: 30, offset: tt.start}
+			got, err := r.Seek(tt.offset, tt.whence)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Seek() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if r.offset != tt.want || (!tt.wantErr && got != tt.want) {
+				t.Errorf("Seek() = %d, offset %d; want %d", got, r.offset, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlobReaderRead(t *testing.T) {
+	// The current chunk covers bytes 30-59, so reads inside it need no fetch
+	r := &blobReader{length: 100, chunkSize: 30, chunk: []byte("abcdefghijklmnopqrstuvwxyz0123"), chunkStart: 30}
+
+	r.Seek(52, io.SeekStart)
+	p := make([]byte, 16)
+	n, err := r.Read(p)
+	if err != nil || string(p[:n]) != "wxyz0123" {
+		t.Errorf("Read() = %q, %v; want the rest of the chunk", p[:n], err)
+	}
+
+	r.Seek(0, io.SeekEnd)
+	if n, err := r.Read(p); n != 0 || err != io.EOF {
+		t.Errorf("Read() at the end = %d, %v; want 0, EOF", n, err)
+	}
+}
+
+func TestBlobSizes(t *testing.T) {
+	thumbnails := []Thumbnail{{Bytes: 5}, {Bytes: 7}}
+	rendition := &Rendition{Size: 300}
+	tests := []struct {
+		name       string
+		blob       Blob
+		wantStored int64
+		wantFiles  int64
+	}{
+		{"upload only", Blob{Size: 1000, Thumbnails: thumbnails}, 1000, 1012},
+		{"original kept", Blob{Size: 1000, Thumbnails: thumbnails, Rendition: rendition}, 1000, 1312},
+		{"original dropped", Blob{Size: 1000, Thumbnails: thumbnails, Rendition: rendition, OriginalDropped: true}, 300, 312},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.blob.storedSize(); got != tt.wantStored {
+				t.Errorf("storedSize() = %d, want %d", got, tt.wantStored)
+			}
+			if got := tt.blob.fileBytes(); got != tt.wantFiles {
+				t.Errorf("fileBytes() = %d, want %d", got, tt.wantFiles)
+			}
+		})
+	}
+}
@@ -0,0 +1,400 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"gochat_server/config"
+	"gochat_server/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// gcBatchSize bounds the $in lists used to look up references
+const gcBatchSize = 500
+
+// objectIDPattern finds media IDs inside the URLs some fields store, such as
+// "/media/image/<id>" profile pictures
+var objectIDPattern = regexp.MustCompile(`[0-9a-f]{24}`)
+
+// GCReport is what one garbage collection run found and deleted. Runs are
+// kept in the media_gc_runs collection.
+type GCReport struct {
+	ID             string `bson:"_id" json:"id"`
+	StartedAt      string `bson:"started_at" json:"started_at"`
+	FinishedAt     string `bson:"finished_at" json:"finished_at"`
+	DryRun         bool   `bson:"dry_run" json:"dry_run"`
+	Scanned        int    `bson:"scanned" json:"scanned"`
+	Deleted        int    `bson:"deleted" json:"deleted"`                 // media records and loose GridFS files
+	ReleasedBytes  int64  `bson:"released_bytes" json:"released_bytes"`   // size of what was deleted, as owners see it
+	ReclaimedBytes int64  `bson:"reclaimed_bytes" json:"reclaimed_bytes"` // storage actually freed, after deduplication
+	Errors         int    `bson:"errors" json:"errors"`
+}
+
+// RunMediaGC periodically deletes media nothing refers to any more
+func RunMediaGC() {
+	if config.Cfg.MediaGCInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(config.Cfg.MediaGCInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		report := collectGarbage(config.Cfg.MediaGCDryRun)
+		fmt.Printf("Media GC (dry run: %v): scanned %d, deleted %d, released %d bytes, reclaimed %d bytes, %d errors\n",
+			report.DryRun, report.Scanned, report.Deleted, report.ReleasedBytes, report.ReclaimedBytes, report.Errors)
+		if _, err := db.GetCollection("media_gc_runs").InsertOne(context.TODO(), report); err != nil {
+			fmt.Println("Failed to record media GC run:", err)
+		}
+	}
+}
+
+// collectGarbage deletes media older than the grace period that no message,
+// scheduled or undelivered message, channel post, profile picture or icon
+// refers to, by media ID or by a URL in its text. GridFS files that belong to
+// no media at all are only collected when MEDIA_GC_LOOSE_FILES is set: files
+// from before the media collection were linked from messages that were never
+// stored on the server. Messages older than the retention period stop
+// counting as references; the offline copy of an undelivered message keeps
+// its media regardless. In a dry run nothing is deleted and the report says
+// what would have been.
+func collectGarbage(dryRun bool) GCReport {
+	report := GCReport{
+		ID:        primitive.NewObjectID().Hex(),
+		StartedAt: time.Now().UTC().Format(time.RFC3339),
+		DryRun:    dryRun,
+	}
+	cutoff := time.Now().UTC().Add(-config.Cfg.MediaGCGrace)
+
+	icons, err := iconReferences()
+	if err != nil {
+		fmt.Println("Error collecting icon references:", err)
+		report.Errors++
+		return report
+	}
+	gc := collector{report: &report, icons: icons, planned: make(map[string]int)}
+
+	gc.sweepRecords(cutoff.Format(time.RFC3339))
+	if config.Cfg.MediaGCLooseFiles {
+		gc.sweepLooseFiles(cutoff)
+	}
+	if dryRun {
+		gc.estimateReclaimed()
+	}
+
+	report.FinishedAt = time.Now().UTC().Format(time.RFC3339)
+	return report
+}
+
+type collector struct {
+	report  *GCReport
+	icons   map[string]bool
+	planned map[string]int   // dry run: references each blob would lose
+	sizes   map[string]int64 // lengths of the GridFS files in the current batch
+}
+
+// sweepRecords deletes unreferenced media records created before cutoff
+func (gc *collector) sweepRecords(cutoff string) {
+	cursor, err := db.GetCollection("media").Find(context.TODO(), bson.M{"created_at": bson.M{"$lt": cutoff}})
+	if err != nil {
+		fmt.Println("Error scanning media:", err)
+		gc.report.Errors++
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	batch := make([]Media, 0, gcBatchSize)
+	flush := func() {
+		gc.collectRecords(batch)
+		batch = batch[:0]
+	}
+	for cursor.Next(context.TODO()) {
+		var media Media
+		if err := cursor.Decode(&media); err != nil {
+			gc.report.Errors++
+			continue
+		}
+		batch = append(batch, media)
+		if len(batch) == gcBatchSize {
+			flush()
+		}
+	}
+	flush()
+}
+
+func (gc *collector) collectRecords(batch []Media) {
+	if len(batch) == 0 {
+		return
+	}
+	ids := make([]string, 0, len(batch))
+	for _, media := range batch {
+		ids = append(ids, media.ID)
+	}
+	referenced, err := gc.referenced(ids)
+	if err != nil {
+		fmt.Println("Error looking up media references:", err)
+		gc.report.Errors++
+		return
+	}
+
+	for _, media := range batch {
+		gc.report.Scanned++
+		if referenced[media.ID] {
+			continue
+		}
+		gc.report.Deleted++
+		gc.report.ReleasedBytes += media.Size
+		if gc.report.DryRun {
+			gc.planned[media.BlobID]++
+			continue
+		}
+		reclaimed, err := deleteMedia(media)
+		if err != nil {
+			fmt.Println("Failed to delete unreferenced media:", err)
+			gc.report.Errors++
+		}
+		gc.report.ReclaimedBytes += reclaimed
+	}
+}
+
+// sweepLooseFiles deletes GridFS files uploaded before cutoff that are neither
+// a blob nor a thumbnail of one, unless a message or icon refers to them
+// directly as files from before the media collection are
+func (gc *collector) sweepLooseFiles(cutoff time.Time) {
+	cursor, err := db.GetCollection("fs.files").Find(
+		context.TODO(),
+		bson.M{"uploadDate": bson.M{"$lt": cutoff}},
+		options.Find().SetProjection(bson.M{"_id": 1, "length": 1}),
+	)
+	if err != nil {
+		fmt.Println("Error scanning GridFS files:", err)
+		gc.report.Errors++
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	gc.sizes = make(map[string]int64)
+	batch := make([]string, 0, gcBatchSize)
+	flush := func() {
+		gc.collectLooseFiles(batch)
+		batch = batch[:0]
+		clear(gc.sizes)
+	}
+	for cursor.Next(context.TODO()) {
+		var f struct {
+			ID     primitive.ObjectID `bson:"_id"`
+			Length int64              `bson:"length"`
+		}
+		if err := cursor.Decode(&f); err != nil {
+			gc.report.Errors++
+			continue
+		}
+		gc.sizes[f.ID.Hex()] = f.Length
+		batch = append(batch, f.ID.Hex())
+		if len(batch) == gcBatchSize {
+			flush()
+		}
+	}
+	flush()
+}
+
+func (gc *collector) collectLooseFiles(batch []string) {
+	if len(batch) == 0 {
+		return
+	}
+	owned, err := ownedFiles(batch)
+	if err != nil {
+		fmt.Println("Error looking up GridFS file owners:", err)
+		gc.report.Errors++
+		return
+	}
+	referenced, err := gc.referenced(batch)
+	if err != nil {
+		fmt.Println("Error looking up media references:", err)
+		gc.report.Errors++
+		return
+	}
+
+	for _, id := range batch {
+		if owned[id] {
+			continue
+		}
+		gc.report.Scanned++
+		if referenced[id] {
+			continue
+		}
+		size := gc.sizes[id]
+		gc.report.Deleted++
+		gc.report.ReleasedBytes += size
+		gc.report.ReclaimedBytes += size
+		if gc.report.DryRun {
+			continue
+		}
+		if err := deleteBlobFiles(id, nil); err != nil {
+			fmt.Println("Failed to delete loose GridFS file:", err)
+			gc.report.Errors++
+			gc.report.ReclaimedBytes -= size
+		}
+	}
+}
+
+// estimateReclaimed works out, for a dry run, which blobs would have lost
+// their last reference
+func (gc *collector) estimateReclaimed() {
+	for blobID, released := range gc.planned {
+		var blob Blob
+		err := db.GetCollection("blobs").FindOne(context.TODO(), bson.M{"_id": blobID}).Decode(&blob)
+		if err == mongo.ErrNoDocuments {
+			// Stored before deduplication: the record owned the file alone
+			var media Media
+			if db.GetCollection("media").FindOne(context.TODO(), bson.M{"blob_id": blobID}).Decode(&media) == nil {
+				gc.report.ReclaimedBytes += media.Size + thumbnailBytes(media.Thumbnails)
+			}
+			continue
+		}
+		if err != nil {
+			gc.report.Errors++
+			continue
+		}
+		if blob.Refs <= int64(released) {
+			gc.report.ReclaimedBytes += blob.fileBytes()
+		}
+	}
+}
+
+// distinct returns the distinct values of field across the documents of a
+// collection that match filter. Tests replace it.
+var distinct = func(collection, field string, filter bson.M) ([]interface{}, error) {
+	return db.GetCollection(collection).Distinct(context.TODO(), field, filter)
+}
+
+// referenced returns which of ids something still refers to, either as a
+// media ID field or inside the text of a message or post, where clients put
+// the URLs of files uploaded through the old routes
+func (gc *collector) referenced(ids []string) (map[string]bool, error) {
+	referenced := make(map[string]bool)
+	for _, id := range ids {
+		if gc.icons[id] {
+			referenced[id] = true
+		}
+	}
+
+	delivered := bson.M{}
+	if retention := config.Cfg.MediaRetention; retention > 0 {
+		delivered["server_ts"] = bson.M{"$gte": time.Now().UTC().Add(-retention).Format(time.RFC3339)}
+	}
+	mentions := bson.M{"$regex": strings.Join(ids, "|")}
+	sources := []struct {
+		collection string
+		field      string
+		filter     bson.M
+	}{
+		{"messages", "media_id", withField(delivered, "media_id", bson.M{"$in": ids})},
+		{"messages", "content", withField(delivered, "content", mentions)},
+		{"offline_messages", "message.data.media_id", bson.M{"message.data.media_id": bson.M{"$in": ids}}},
+		{"offline_messages", "message.data.content", bson.M{"message.data.content": mentions}},
+		{"scheduled_messages", "message.media_id", bson.M{"message.media_id": bson.M{"$in": ids}, "status": "pending"}},
+		{"scheduled_messages", "message.content", bson.M{"message.content": mentions, "status": "pending"}},
+		{"channel_posts", "media_id", bson.M{"media_id": bson.M{"$in": ids}}},
+		{"channel_posts", "content", bson.M{"content": mentions}},
+	}
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	for _, source := range sources {
+		values, err := distinct(source.collection, source.field, source.filter)
+		if err != nil {
+			return nil, err
+		}
+		for id := range idsIn(values) {
+			if wanted[id] {
+				referenced[id] = true
+			}
+		}
+	}
+	return referenced, nil
+}
+
+// withField copies filter with one more condition
+func withField(filter bson.M, field string, condition interface{}) bson.M {
+	copied := bson.M{field: condition}
+	for key, value := range filter {
+		copied[key] = value
+	}
+	return copied
+}
+
+// iconReferences collects the media IDs used as profile pictures and group,
+// channel and community icons. These fields hold either a media ID or a URL
+// containing one.
+func iconReferences() (map[string]bool, error) {
+	icons := make(map[string]bool)
+	sources := []struct {
+		collection string
+		field      string
+	}{
+		{"users", "profile_picture_url"},
+		{"groups", "group_icon"},
+		{"channels", "icon"},
+		{"communities", "icon"},
+	}
+	for _, source := range sources {
+		values, err := distinct(source.collection, source.field, bson.M{source.field: bson.M{"$nin": bson.A{"", nil}}})
+		if err != nil {
+			return nil, err
+		}
+		for id := range idsIn(values) {
+			icons[id] = true
+		}
+	}
+	return icons, nil
+}
+
+// idsIn finds the media IDs in string values, which are either IDs or text
+// containing them
+func idsIn(values []interface{}) map[string]bool {
+	ids := make(map[string]bool)
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			for _, id := range objectIDPattern.FindAllString(s, -1) {
+				ids[id] = true
+			}
+		}
+	}
+	return ids
+}
+
+// ownedFiles returns which of the GridFS file IDs are blobs, or thumbnails or
+// renditions of a blob or media record, and so are collected along with their media
+func ownedFiles(ids []string) (map[string]bool, error) {
+	owned := make(map[string]bool)
+	sources := []struct {
+		collection string
+		field      string
+	}{
+		{"blobs", "_id"},
+		{"blobs", "thumbnails.blob_id"},
+		{"blobs", "rendition.blob_id"},
+		{"media", "blob_id"},
+		{"media", "thumbnails.blob_id"},
+	}
+	for _, source := range sources {
+		values, err := distinct(source.collection, source.field, bson.M{source.field: bson.M{"$in": ids}})
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range values {
+			if id, ok := value.(string); ok {
+				owned[id] = true
+			}
+		}
+	}
+	return owned, nil
+}
@@ -0,0 +1,102 @@
+package media
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	idSent     = "aaaaaaaaaaaaaaaaaaaaaaaa"
+	idLinked   = "bbbbbbbbbbbbbbbbbbbbbbbb"
+	idQueued   = "cccccccccccccccccccccccc"
+	idPosted   = "dddddddddddddddddddddddd"
+	idIcon     = "eeeeeeeeeeeeeeeeeeeeeeee"
+	idOrphan   = "ffffffffffffffffffffffff"
+	idAvatar   = "0123456789abcdef01234567"
+	idNotAsked = "111111111111111111111111"
+)
+
+// fakeDistinct answers distinct lookups from fixed values per collection and
+// field, and records the filters it was asked with
+func fakeDistinct(t *testing.T, values map[string][]interface{}) map[string]bson.M {
+	t.Helper()
+	filters := make(map[string]bson.M)
+	original := distinct
+	t.Cleanup(func() { distinct = original })
+	distinct = func(collection, field string, filter bson.M) ([]interface{}, error) {
+		filters[collection+"."+field] = filter
+		return values[collection+"."+field], nil
+	}
+	return filters
+}
+
+func TestReferenced(t *testing.T) {
+	filters := fakeDistinct(t, map[string][]interface{}{
+		"messages.media_id":                      {idSent},
+		"messages.content":                       {"look: https://chat.example/api/media/image/" + idLinked + " and " + idNotAsked},
+		"offline_messages.message.data.media_id": {idQueued},
+		"channel_posts.content":                  {"/api/file/download/" + idPosted},
+		"scheduled_messages.message.media_id":    {nil, 42},
+	})
+
+	gc := collector{icons: map[string]bool{idIcon: true}}
+	ids := []string{idSent, idLinked, idQueued, idPosted, idIcon, idOrphan}
+	got, err := gc.referenced(ids)
+	if err != nil {
+		t.Fatalf("referenced() error = %v", err)
+	}
+
+	want := map[string]bool{idSent: true, idLinked: true, idQueued: true, idPosted: true, idIcon: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("referenced() = %v, want %v", got, want)
+	}
+
+	// Text is only searched for the batch's IDs
+	content, ok := filters["messages.content"]["content"].(bson.M)
+	if !ok || content["$regex"] != idSent+"|"+idLinked+"|"+idQueued+"|"+idPosted+"|"+idIcon+"|"+idOrphan {
+		t.Errorf("messages.content filter = %v, want a regex of the batch IDs", filters["messages.content"])
+	}
+	if status := filters["scheduled_messages.message.content"]["status"]; status != "pending" {
+		t.Errorf("scheduled message text filter status = %v, want pending", status)
+	}
+}
+
+func TestIconReferences(t *testing.T) {
+	fakeDistinct(t, map[string][]interface{}{
+		"users.profile_picture_url": {"/api/media/" + idAvatar, "https://cdn.example/not-ours.png"},
+		"groups.group_icon":         {idIcon},
+		"channels.icon":             {"/media/image/" + idPosted},
+	})
+
+	got, err := iconReferences()
+	if err != nil {
+		t.Fatalf("iconReferences() error = %v", err)
+	}
+	want := map[string]bool{idAvatar: true, idIcon: true, idPosted: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("iconReferences() = %v, want %v", got, want)
+	}
+}
+
+func TestIDsIn(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []interface{}
+		want   map[string]bool
+	}{
+		{"bare ID", []interface{}{idSent}, map[string]bool{idSent: true}},
+		{"URL", []interface{}{"/media/image/" + idSent + "?size=96"}, map[string]bool{idSent: true}},
+		{"several in text", []interface{}{idSent + " then " + idLinked}, map[string]bool{idSent: true, idLinked: true}},
+		{"no ID", []interface{}{"hello", ""}, map[string]bool{}},
+		{"not a string", []interface{}{nil, 7, bson.M{"id": idSent}}, map[string]bool{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := idsIn(tt.values); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("idsIn() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
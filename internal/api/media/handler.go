@@ -1,93 +1,265 @@
 package media
 
 import (
-	"bytes"
-	"fmt"
-	"gochat_server/internal/db"
-	"io"
+	"mime"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gochat_server/config"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
-// UploadImage uploads an image to MongoDB GridFS and returns the image URL
-func UploadImage(c *gin.Context) {
-	// Get the file from the request
-	file, header, err := c.Request.FormFile("image")
+// Upload stores a file sent as the "file" form field and returns its media
+// record
+func Upload(c *gin.Context) {
+	media, ok := receiveUpload(c, "file")
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, media)
+}
+
+// Download streams a media's bytes with its stored Content-Type. A transcoded
+// video is served as its rendition unless ?original=true.
+func Download(c *gin.Context) {
+	serveMedia(c, c.Param("id"))
+}
+
+// GetMediaInfo returns a media's metadata record
+func GetMediaInfo(c *gin.Context) {
+	media, err := findMedia(c.Param("id"))
+	if err == ErrNotFound || (err == nil && !canAccess(tokenUserID(c), media)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get image file"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch media"})
 		return
 	}
-	defer file.Close()
 
-	// Get GridFS bucket
-	bucket, err := gridfs.NewBucket(db.GetDB())
+	c.JSON(http.StatusOK, media)
+}
+
+// GetSignedURL returns short-lived signed URLs for a media and its thumbnails,
+// for clients that can't attach credentials (image loaders, notifications).
+// ?ttl= sets the lifetime, up to 24h.
+func GetSignedURL(c *gin.Context) {
+	media, err := findMedia(c.Param("id"))
+	if err == ErrNotFound || (err == nil && !canAccess(tokenUserID(c), media)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to initialize GridFS"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch media"})
+		return
+	}
+
+	ttl := config.Cfg.MediaURLTTL
+	if value := c.Query("ttl"); value != "" {
+		ttl, err = time.ParseDuration(value)
+		if err != nil || ttl <= 0 || ttl > maxSignedURLTTL {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ttl must be a duration up to 24h"})
+			return
+		}
+	}
+	expiresAt := time.Now().Add(ttl)
+
+	thumbnails := make(map[int]string)
+	for _, thumbnail := range media.Thumbnails {
+		thumbnails[thumbnail.Size] = signURL(thumbnail.URL, media.ID, expiresAt)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"url":        signURL("/media/"+media.ID, media.ID, expiresAt),
+		"thumbnails": thumbnails,
+		"expires_at": expiresAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// HasHash answers whether the caller already has content with the given
+// SHA-256 stored, so they can skip uploading it again and use CreateFromHash
+// instead. Only the caller's own uploads count: knowing a hash must not reveal
+// or hand out anyone else's file.
+func HasHash(c *gin.Context) {
+	userID := callerID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing user_id"})
 		return
 	}
 
-	// Read file into a buffer
-	var buf bytes.Buffer
-	_, err = io.Copy(&buf, file)
+	owned, err := ownsContent(userID, strings.ToLower(c.Param("sha256")))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up hash"})
+		return
+	}
+	if !owned {
+		c.JSON(http.StatusNotFound, gin.H{"exists": false})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"exists": true})
+}
+
+// CreateFromHash makes a media record for content the caller has uploaded
+// before, without uploading it again. It answers 404 when the caller has no
+// such content and the file has to be uploaded.
+func CreateFromHash(c *gin.Context) {
+	userID := callerID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing user_id"})
+		return
+	}
+
+	var request FromHashRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
 
-	// Upload the file to GridFS
-	uploadStream, err := bucket.OpenUploadStream(header.Filename)
+	hash := strings.ToLower(request.Hash)
+	owned, err := ownsContent(userID, hash)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up hash"})
+		return
+	}
+	if !owned {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No file with this hash; upload it instead"})
+		return
+	}
+	blob, err := acquireBlob(hash)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No file with this hash; upload it instead"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload image"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up hash"})
 		return
 	}
-	defer uploadStream.Close()
 
-	_, err = uploadStream.Write(buf.Bytes())
+	// The limits may have changed since the content was first uploaded
+	err = checkUpload(blob.MimeType, blob.MediaType, blob.Size)
+	if err == nil {
+		err = reserveStorage(userID, blob.storedSize())
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write image to GridFS"})
+		releaseBlob(blob.ID)
+		writeUploadError(c, err)
 		return
 	}
 
-	// Get the image ID
-	imageID := uploadStream.FileID.(primitive.ObjectID).Hex()
+	media, err := createRecord(userID, request.FileName, blob)
+	if err != nil {
+		releaseBlob(blob.ID)
+		releaseStorage(userID, blob.storedSize())
+		writeUploadError(c, err)
+		return
+	}
 
-	// Generate a URL (Assuming an endpoint will serve images)
-	imageURL := fmt.Sprintf("/media/image/%s", imageID)
+	c.JSON(http.StatusCreated, media)
+}
 
-	// Return the URL in response
-	c.JSON(http.StatusOK, gin.H{"image_url": imageURL})
+// receiveUpload spools the multipart file in field and ingests it as the
+// caller's, writing the error response when that fails
+func receiveUpload(c *gin.Context, field string) (Media, bool) {
+	ownerID := callerID(c)
+	if ownerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing user_id"})
+		return Media{}, false
+	}
+	return receiveFile(c, field, ownerID)
 }
 
-// ServeImage retrieves an image from MongoDB GridFS
-func ServeImage(c *gin.Context) {
-	imageID := c.Param("id")
+// receiveFile spools the multipart file in field and ingests it for ownerID,
+// writing the error response when that fails
+func receiveFile(c *gin.Context, field, ownerID string) (Media, bool) {
 
-	// Convert ID to ObjectID
-	objID, err := primitive.ObjectIDFromHex(imageID)
+	upload, header, err := c.Request.FormFile(field)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image ID"})
-		return
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file upload"})
+		return Media{}, false
+	}
+	defer upload.Close()
+	if header.Size > maxUploadSize() {
+		writeUploadError(c, ErrTooLarge)
+		return Media{}, false
 	}
 
-	// Get GridFS bucket
-	bucket, err := gridfs.NewBucket(db.GetDB())
+	path, size, hash, err := spool(upload)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to initialize GridFS"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reading file"})
+		return Media{}, false
+	}
+	defer os.Remove(path)
+
+	media, err := ingest(ownerID, header.Filename, path, size, hash)
+	if err != nil {
+		writeUploadError(c, err)
+		return Media{}, false
+	}
+	return media, true
+}
+
+// serveMedia streams a media with http.ServeContent, which answers Range
+// (single and multiple), If-Range, If-None-Match and If-Modified-Since
+// requests with the right 206, 304 or 416 status
+func serveMedia(c *gin.Context, mediaID string) {
+	media, err := findMedia(mediaID)
+	if err == ErrNotFound || (err == nil && !authorize(c, media)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch file"})
 		return
 	}
 
-	// Open a download stream
-	var buf bytes.Buffer
-	_, err = bucket.DownloadToStream(objID, &buf)
+	// Transcoded videos are served as their rendition unless the original
+	// is asked for and was kept. The same URL switches to the rendition once
+	// it is ready, so its ETag names the rendition and it has no
+	// Last-Modified a client could revalidate on.
+	blobID, mimeType, fileName := media.BlobID, media.MimeType, media.FileName
+	modTime, _ := time.Parse(time.RFC3339, media.CreatedAt)
+	if media.Rendition != nil && (c.Query("original") != "true" || media.OriginalDropped) {
+		blobID, mimeType = media.Rendition.BlobID, media.Rendition.MimeType
+		modTime = time.Time{}
+		if fileName != "" {
+			fileName = strings.TrimSuffix(fileName, filepath.Ext(fileName)) + ".mp4"
+		}
+	}
+
+	blob, err := openBlobReader(c.Request.Context(), blobID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
 		return
 	}
+	defer blob.Close()
+
+	// Old uploads may not have recorded a MIME type; ServeContent sniffs it
+	// when the header is left unset
+	if mimeType != "" {
+		c.Header("Content-Type", mimeType)
+	}
+	if fileName != "" {
+		c.Header("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": fileName}))
+	}
+	c.Header("ETag", etag(media.ID, blobID))
+	// What a URL serves can change (a rendition replacing the original, access
+	// being revoked), so clients revalidate with the ETag before reusing it
+	c.Header("Cache-Control", "private, no-cache")
+
+	http.ServeContent(c.Writer, c.Request, fileName, modTime, blob)
+}
 
-	// Serve the image
-	c.Header("Content-Type", "image/jpeg")
-	c.Writer.Write(buf.Bytes())
+// etag identifies the file served for a media. Stored files never change,
+// so the media and file IDs make a strong validator that, unlike the content
+// hash, tells nothing about the content and differs between the original and
+// its rendition.
+func etag(mediaID, blobID string) string {
+	return `"` + mediaID + "-" + blobID + `"`
 }
@@ -0,0 +1,27 @@
+package media
+
+import "testing"
+
+func TestETags(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"original", etag("m1", "b1"), `"m1-b1"`},
+		{"legacy upload", etag("m2", "m2"), `"m2-m2"`},
+		{"rendition", etag("m3", "r3"), `"m3-r3"`},
+		{"new rendition", etag("m3", "r4"), `"m3-r4"`},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: ETag = %s, want %s", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// Records sharing a blob don't share an ETag, so one can't be used to
+	// find out what another holds
+	if etag("m1", "b1") == etag("m4", "b1") {
+		t.Errorf("records of the same blob share the ETag %s", etag("m1", "b1"))
+	}
+}
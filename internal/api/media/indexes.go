@@ -0,0 +1,55 @@
+package media
+
+import (
+	"context"
+	"fmt"
+
+	"gochat_server/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureIndexes creates the indexes the media collections rely on
+func EnsureIndexes() {
+	indexes := map[string][]mongo.IndexModel{
+		"media": {
+			{Keys: bson.D{{Key: "owner_id", Value: 1}, {Key: "created_at", Value: -1}}},
+			{Keys: bson.D{{Key: "sha256", Value: 1}, {Key: "owner_id", Value: 1}}},
+			{Keys: bson.D{{Key: "blob_id", Value: 1}}},
+			{Keys: bson.D{{Key: "thumbnails.blob_id", Value: 1}}},
+			{Keys: bson.D{{Key: "created_at", Value: 1}}},
+		},
+		"blobs": {
+			{
+				Keys:    bson.D{{Key: "sha256", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "thumbnails.blob_id", Value: 1}}},
+			{Keys: bson.D{{Key: "rendition.blob_id", Value: 1}}, Options: options.Index().SetSparse(true)},
+		},
+		"jobs": {
+			{Keys: bson.D{{Key: "type", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: 1}}},
+			{
+				Keys:    bson.D{{Key: "type", Value: 1}, {Key: "blob_id", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+		},
+		"upload_sessions": {
+			{Keys: bson.D{{Key: "updated_at", Value: 1}}},
+		},
+		"upload_chunks": {
+			{
+				Keys:    bson.D{{Key: "session_id", Value: 1}, {Key: "offset", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+		},
+	}
+
+	for collection, models := range indexes {
+		if _, err := db.GetCollection(collection).Indexes().CreateMany(context.TODO(), models); err != nil {
+			fmt.Printf("Failed to create indexes on %s: %v\n", collection, err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package media
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The handlers below keep the routes of the old image and file upload stacks
+// working on top of the media service.
+
+// UploadImage accepts an "image" form field and answers with the URL the old
+// image endpoint returned, alongside the media record
+func UploadImage(c *gin.Context) {
+	media, ok := receiveLegacyUpload(c, "image")
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"image_url": fmt.Sprintf("/media/image/%s", media.ID),
+		"media":     media,
+	})
+}
+
+// ServeImage serves a media by its :id
+func ServeImage(c *gin.Context) {
+	serveMedia(c, c.Param("id"))
+}
+
+// UploadFile accepts a "file" form field, as the old file endpoint did
+func UploadFile(c *gin.Context) {
+	media, ok := receiveLegacyUpload(c, "file")
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, media)
+}
+
+// receiveLegacyUpload ingests an upload made through the old routes, whose
+// clients never identified themselves. It is owned by the token's user when
+// there is one; otherwise it has no owner and, as on the old stack, anyone
+// with its URL can download it.
+func receiveLegacyUpload(c *gin.Context, field string) (Media, bool) {
+	ownerID := tokenUserID(c)
+	media, ok := receiveFile(c, field, ownerID)
+	if ok && ownerID == "" {
+		if err := MakePublic(media.ID); err != nil {
+			fmt.Println("Failed to publish ownerless upload:", err)
+		}
+		media.Public = true
+	}
+	return media, ok
+}
+
+// DownloadFile serves a media by its :file_id
+func DownloadFile(c *gin.Context) {
+	serveMedia(c, c.Param("file_id"))
+}
@@ -0,0 +1,216 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"gochat_server/config"
+	"gochat_server/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrTooLarge       = errors.New("file is too large")
+	ErrTypeNotAllowed = errors.New("file type is not allowed")
+	ErrQuotaExceeded  = errors.New("storage quota exceeded")
+)
+
+// StorageUsage is a user's running media storage total, kept in user_storage
+type StorageUsage struct {
+	UserID    string `bson:"_id" json:"user_id"`
+	UsedBytes int64  `bson:"used_bytes" json:"used_bytes"`
+	FileCount int64  `bson:"file_count" json:"file_count"`
+	UpdatedAt string `bson:"updated_at" json:"updated_at"`
+}
+
+// maxSizeFor returns the configured size limit for a media type
+func maxSizeFor(mediaType string) int64 {
+	switch mediaType {
+	case "image":
+		return config.Cfg.MaxImageSize
+	case "video":
+		return config.Cfg.MaxVideoSize
+	case "audio":
+		return config.Cfg.MaxAudioSize
+	default:
+		return config.Cfg.MaxDocumentSize
+	}
+}
+
+// maxUploadSize is the largest file of any type, for rejecting uploads before
+// their type is known
+func maxUploadSize() int64 {
+	return max(config.Cfg.MaxImageSize, config.Cfg.MaxVideoSize, config.Cfg.MaxAudioSize, config.Cfg.MaxDocumentSize)
+}
+
+// checkUpload applies the MIME type lists and the size limit of the file's
+// media type
+func checkUpload(mimeType, mediaType string, size int64) error {
+	if !mimeAllowed(mimeType) {
+		return fmt.Errorf("%w: %s", ErrTypeNotAllowed, mimeType)
+	}
+	if limit := maxSizeFor(mediaType); size > limit {
+		return fmt.Errorf("%w: %s files can be at most %d bytes", ErrTooLarge, mediaType, limit)
+	}
+	return nil
+}
+
+// mimeAllowed checks a MIME type against the deny list, then the allow list
+// when one is configured
+func mimeAllowed(mimeType string) bool {
+	mimeType = strings.ToLower(strings.TrimSpace(strings.Split(mimeType, ";")[0]))
+	if matchesMimeList(mimeType, config.Cfg.DeniedMimeTypes) {
+		return false
+	}
+	return len(config.Cfg.AllowedMimeTypes) == 0 || matchesMimeList(mimeType, config.Cfg.AllowedMimeTypes)
+}
+
+// matchesMimeList reports whether mimeType is in list, where "image/*" style
+// entries match a whole top-level type
+func matchesMimeList(mimeType string, list []string) bool {
+	for _, entry := range list {
+		if entry == mimeType || entry == "*/*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(entry, "/*"); ok && strings.HasPrefix(mimeType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasRoomFor reports whether a user's quota has size bytes left, without
+// reserving them
+func hasRoomFor(userID string, size int64) bool {
+	quota := config.Cfg.UserStorageQuota
+	if quota == 0 {
+		return true
+	}
+	return storageUsage(userID).UsedBytes+size <= quota
+}
+
+// reserveStorage adds size bytes to a user's usage, failing with
+// ErrQuotaExceeded when that would take them over their quota. The check and
+// the increment are a single update, so concurrent uploads can't overshoot.
+func reserveStorage(userID string, size int64) error {
+	if userID == "" {
+		// Uploads through the old routes have no owner to charge
+		return nil
+	}
+	quota := config.Cfg.UserStorageQuota
+	if quota > 0 && size > quota {
+		return ErrQuotaExceeded
+	}
+
+	filter := bson.M{"_id": userID}
+	if quota > 0 {
+		filter["used_bytes"] = bson.M{"$lte": quota - size}
+	}
+	_, err := db.GetCollection("user_storage").UpdateOne(
+		context.TODO(),
+		filter,
+		bson.M{
+			"$inc": bson.M{"used_bytes": size, "file_count": 1},
+			"$set": bson.M{"updated_at": time.Now().UTC().Format(time.RFC3339)},
+		},
+		options.Update().SetUpsert(true),
+	)
+	// The filter only misses an existing document when it is over quota, and
+	// the upsert then collides with it on _id
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrQuotaExceeded
+	}
+	return err
+}
+
+// releaseStorage takes size bytes of a deleted or failed upload off a user's
+// usage
+func releaseStorage(userID string, size int64) {
+	changeStorage(userID, -size, -1)
+}
+
+// adjustStorage changes a user's usage by delta bytes for a file they keep,
+// as when a video's upload is dropped for its smaller rendition
+func adjustStorage(userID string, delta int64) {
+	changeStorage(userID, delta, 0)
+}
+
+func changeStorage(userID string, bytes, files int64) {
+	if userID == "" || (bytes == 0 && files == 0) {
+		return
+	}
+	_, err := db.GetCollection("user_storage").UpdateOne(
+		context.TODO(),
+		bson.M{"_id": userID},
+		bson.M{
+			"$inc": bson.M{"used_bytes": bytes, "file_count": files},
+			"$set": bson.M{"updated_at": time.Now().UTC().Format(time.RFC3339)},
+		},
+	)
+	if err != nil {
+		fmt.Println("Failed to update storage usage:", err)
+	}
+}
+
+func storageUsage(userID string) StorageUsage {
+	usage := StorageUsage{UserID: userID}
+	db.GetCollection("user_storage").FindOne(context.TODO(), bson.M{"_id": userID}).Decode(&usage)
+	return usage
+}
+
+// GetStorageUsage reports how much media the caller stores and what is left
+// of their quota
+func GetStorageUsage(c *gin.Context) {
+	userID := callerID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing user_id"})
+		return
+	}
+
+	usage := storageUsage(userID)
+	response := gin.H{
+		"used_bytes":  usage.UsedBytes,
+		"file_count":  usage.FileCount,
+		"quota_bytes": config.Cfg.UserStorageQuota,
+		"limits": gin.H{
+			"image":    config.Cfg.MaxImageSize,
+			"video":    config.Cfg.MaxVideoSize,
+			"audio":    config.Cfg.MaxAudioSize,
+			"document": config.Cfg.MaxDocumentSize,
+		},
+	}
+	if quota := config.Cfg.UserStorageQuota; quota > 0 {
+		response["remaining_bytes"] = max(quota-usage.UsedBytes, 0)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// writeUploadError answers a failed upload with the status its error calls
+// for
+func writeUploadError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrTypeNotAllowed):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrQuotaExceeded):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		fmt.Println("Failed to store media:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store file"})
+	}
+}
+
+// isRejection reports whether an upload failed validation rather than
+// storage, so retrying it can't succeed
+func isRejection(err error) bool {
+	return errors.Is(err, ErrTooLarge) || errors.Is(err, ErrTypeNotAllowed) || errors.Is(err, ErrQuotaExceeded)
+}
@@ -0,0 +1,47 @@
+package media
+
+import (
+	"errors"
+	"testing"
+
+	"gochat_server/config"
+)
+
+func TestCheckUpload(t *testing.T) {
+	defer func(cfg config.Config) { config.Cfg = cfg }(config.Cfg)
+	config.Cfg.MaxImageSize = 100
+	config.Cfg.MaxVideoSize = 1000
+	config.Cfg.MaxAudioSize = 500
+	config.Cfg.MaxDocumentSize = 200
+	config.Cfg.DeniedMimeTypes = []string{"application/x-msdownload", "text/html"}
+
+	tests := []struct {
+		name      string
+		allowed   []string
+		mimeType  string
+		mediaType string
+		size      int64
+		want      error
+	}{
+		{"image within limit", nil, "image/png", "image", 100, nil},
+		{"image too large", nil, "image/png", "image", 101, ErrTooLarge},
+		{"video uses its own limit", nil, "video/mp4", "video", 1000, nil},
+		{"document limit", nil, "application/pdf", "document", 201, ErrTooLarge},
+		{"denied type", nil, "application/x-msdownload", "document", 1, ErrTypeNotAllowed},
+		{"denied type with parameters", nil, "text/html; charset=utf-8", "document", 1, ErrTypeNotAllowed},
+		{"denied type in other case", nil, "TEXT/HTML", "document", 1, ErrTypeNotAllowed},
+		{"allow list wildcard", []string{"image/*"}, "image/jpeg", "image", 1, nil},
+		{"outside allow list", []string{"image/*"}, "video/mp4", "video", 1, ErrTypeNotAllowed},
+		{"deny list wins over allow list", []string{"*/*"}, "text/html", "document", 1, ErrTypeNotAllowed},
+		{"wildcard needs a whole top-level type", []string{"image/*"}, "imagex/png", "document", 1, ErrTypeNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Cfg.AllowedMimeTypes = tt.allowed
+			err := checkUpload(tt.mimeType, tt.mediaType, tt.size)
+			if !errors.Is(err, tt.want) || (tt.want == nil) != (err == nil) {
+				t.Errorf("checkUpload(%q, %q, %d) = %v, want %v", tt.mimeType, tt.mediaType, tt.size, err, tt.want)
+			}
+		})
+	}
+}
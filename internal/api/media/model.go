@@ -0,0 +1,154 @@
+package media
+
+// Media is the metadata record of an uploaded file. The bytes live in GridFS
+// as BlobID, which every record of identical content shares. Records made
+// before deduplication have an ID equal to their BlobID.
+type Media struct {
+	ID        string `bson:"_id" json:"media_id"`
+	BlobID    string `bson:"blob_id" json:"-"`
+	OwnerID   string `bson:"owner_id" json:"owner_id"`
+	FileName  string `bson:"file_name" json:"file_name"`
+	MimeType  string `bson:"mime_type" json:"mime_type"`
+	MediaType string `bson:"media_type" json:"media_type"` // image, video, audio or document
+	Size      int64  `bson:"size" json:"size"`
+	Width     int    `bson:"width,omitempty" json:"width"`
+	Height    int    `bson:"height,omitempty" json:"height"`
+	BlurHash  string `bson:"blur_hash,omitempty" json:"blur_hash"`
+	Hash      string `bson:"sha256" json:"-"` // not shown, so it can't be used to claim the content
+	CreatedAt string `bson:"created_at" json:"created_at"`
+
+	// Audio and video only
+	Duration float64 `bson:"duration,omitempty" json:"duration,omitempty"` // seconds
+	Codec    string  `bson:"codec,omitempty" json:"codec,omitempty"`
+	Bitrate  int64   `bson:"bitrate,omitempty" json:"bitrate,omitempty"`   // bits per second
+	Waveform []int   `bson:"waveform,omitempty" json:"waveform,omitempty"` // audio loudness in 64 buckets, 0-100
+
+	Thumbnails []Thumbnail `bson:"thumbnails,omitempty" json:"thumbnails"`
+	Rendition  *Rendition  `bson:"rendition,omitempty" json:"rendition,omitempty"` // set once a video is transcoded
+
+	// OriginalDropped is set when the upload was deleted once transcoded, so
+	// only the rendition is left; Size is then the rendition's
+	OriginalDropped bool `bson:"original_dropped,omitempty" json:"original_dropped,omitempty"`
+
+	// Who besides the owner may download it: the users and groups of the
+	// chats it was sent in, or everyone for public media such as group icons
+	SharedUsers  []string `bson:"shared_users,omitempty" json:"-"`
+	SharedGroups []string `bson:"shared_groups,omitempty" json:"-"`
+	Public       bool     `bson:"public,omitempty" json:"-"`
+
+	legacy bool // a file from before the media collection, with no record
+}
+
+// Thumbnail is a JPEG preview of an image or video, stored in GridFS
+type Thumbnail struct {
+	Size   int    `bson:"size" json:"size"` // the box it was fitted in
+	BlobID string `bson:"blob_id" json:"-"`
+	Width  int    `bson:"width" json:"width"`
+	Height int    `bson:"height" json:"height"`
+	Bytes  int64  `bson:"bytes" json:"bytes"`
+	URL    string `bson:"-" json:"url"`
+}
+
+// Rendition is the mobile-friendly H.264/AAC MP4 a video is transcoded to,
+// stored in GridFS next to (or, when originals aren't kept, instead of) the
+// upload. Downloads serve it unless the original is asked for.
+type Rendition struct {
+	BlobID   string `bson:"blob_id" json:"-"`
+	MimeType string `bson:"mime_type" json:"mime_type"`
+	Size     int64  `bson:"size" json:"size"`
+	Width    int    `bson:"width" json:"width"`
+	Height   int    `bson:"height" json:"height"`
+	Codec    string `bson:"codec" json:"codec"`
+	Bitrate  int64  `bson:"bitrate" json:"bitrate"`
+}
+
+// Blob is a file stored in GridFS, shared by every media record with the same
+// SHA-256. Refs counts those records; the file is deleted when it drops to
+// zero. What can be derived from the bytes is kept here so a new record for
+// known content needs no processing.
+type Blob struct {
+	ID         string      `bson:"_id"` // the GridFS file ID
+	Hash       string      `bson:"sha256"`
+	Size       int64       `bson:"size"`
+	MimeType   string      `bson:"mime_type"`
+	MediaType  string      `bson:"media_type"`
+	Width      int         `bson:"width,omitempty"`
+	Height     int         `bson:"height,omitempty"`
+	BlurHash   string      `bson:"blur_hash,omitempty"`
+	Duration   float64     `bson:"duration,omitempty"`
+	Codec      string      `bson:"codec,omitempty"`
+	Bitrate    int64       `bson:"bitrate,omitempty"`
+	Waveform   []int       `bson:"waveform,omitempty"`
+	Thumbnails []Thumbnail `bson:"thumbnails,omitempty"`
+	Rendition  *Rendition  `bson:"rendition,omitempty"`
+	Refs       int64       `bson:"refs"`
+	CreatedAt  string      `bson:"created_at"`
+
+	// OriginalDropped is set once the uploaded file was deleted in favour of
+	// the rendition. Size stays the upload's, which is what limits apply to.
+	OriginalDropped bool `bson:"original_dropped,omitempty"`
+}
+
+// storedSize is how many bytes a record of the blob holds: the upload, or its
+// rendition once the upload was dropped
+func (b Blob) storedSize() int64 {
+	if b.OriginalDropped && b.Rendition != nil {
+		return b.Rendition.Size
+	}
+	return b.Size
+}
+
+// fileBytes is the total size of the blob's files still in GridFS
+func (b Blob) fileBytes() int64 {
+	size := thumbnailBytes(b.Thumbnails)
+	if !b.OriginalDropped {
+		size += b.Size
+	}
+	if b.Rendition != nil {
+		size += b.Rendition.Size
+	}
+	return size
+}
+
+// UploadSession is a resumable upload in progress. Chunks are kept in the
+// upload_chunks collection, keyed by their offset, until the upload is
+// finalized into GridFS.
+type UploadSession struct {
+	ID        string `bson:"_id" json:"upload_id"`
+	OwnerID   string `bson:"owner_id" json:"owner_id"`
+	FileName  string `bson:"file_name" json:"file_name"`
+	Size      int64  `bson:"size" json:"size"`
+	Offset    int64  `bson:"offset" json:"offset"`
+	Status    string `bson:"status" json:"status"` // open or finalizing
+	CreatedAt string `bson:"created_at" json:"created_at"`
+	UpdatedAt string `bson:"updated_at" json:"updated_at"`
+}
+
+// Job is a unit of background media work, such as transcoding a video, kept
+// in the jobs collection. Workers claim queued jobs with a lease, so a job
+// whose worker died is picked up again once the lease runs out.
+type Job struct {
+	ID         string `bson:"_id" json:"job_id"`
+	Type       string `bson:"type" json:"type"`
+	MediaID    string `bson:"media_id" json:"media_id"` // the upload that queued it
+	BlobID     string `bson:"blob_id" json:"-"`
+	OwnerID    string `bson:"owner_id" json:"owner_id"`
+	Status     string `bson:"status" json:"status"` // queued, running, done or failed
+	Progress   int    `bson:"progress" json:"progress"`
+	Attempts   int    `bson:"attempts" json:"attempts"`
+	Error      string `bson:"error,omitempty" json:"error,omitempty"`
+	LeaseOwner string `bson:"lease_owner,omitempty" json:"-"`
+	LeaseUntil string `bson:"lease_until,omitempty" json:"-"`
+	CreatedAt  string `bson:"created_at" json:"created_at"`
+	UpdatedAt  string `bson:"updated_at" json:"updated_at"`
+}
+
+type CreateUploadRequest struct {
+	FileName string `json:"file_name" binding:"required"`
+	Size     int64  `json:"size" binding:"required"`
+}
+
+type FromHashRequest struct {
+	Hash     string `json:"sha256" binding:"required"`
+	FileName string `json:"file_name" binding:"required"`
+}
@@ -0,0 +1,15 @@
+package media
+
+// Notifier delivers a JSON event to a user over WebSocket, queueing it when
+// they are offline
+type Notifier func(userID string, data interface{}) error
+
+// notify is wired to the WebSocket layer at startup; the media package cannot
+// import it directly because the WebSocket handlers depend on media
+var notify Notifier = func(string, interface{}) error { return nil }
+
+// SetNotifier sets how media events, such as transcoding progress, reach the
+// uploader
+func SetNotifier(n Notifier) {
+	notify = n
+}
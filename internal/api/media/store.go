@@ -0,0 +1,389 @@
+package media
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"time"
+
+	"gochat_server/internal/api/file"
+	"gochat_server/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrNotFound = errors.New("media not found")
+
+// spool copies r to a temporary file, hashing it on the way. The caller
+// removes the file.
+func spool(r io.Reader) (path string, size int64, hash string, err error) {
+	tmp, err := os.CreateTemp("", "media-*")
+	if err != nil {
+		return "", 0, "", err
+	}
+	defer tmp.Close()
+
+	hasher := sha256.New()
+	size, err = io.Copy(io.MultiWriter(tmp, hasher), r)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", 0, "", err
+	}
+	return tmp.Name(), size, hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// ingest validates the spooled file at path against the type and size limits
+// and the owner's quota, then records it as a new media owned by ownerID. When
+// the same content is already stored the record points at the existing blob
+// instead of storing it again.
+func ingest(ownerID, fileName, path string, size int64, hash string) (Media, error) {
+	mimeType, err := file.DetectFileMimeType(path)
+	if err != nil {
+		return Media{}, err
+	}
+	mediaType := file.ClassifyMediaType(mimeType)
+	if err := checkUpload(mimeType, mediaType, size); err != nil {
+		return Media{}, err
+	}
+	if err := reserveStorage(ownerID, size); err != nil {
+		return Media{}, err
+	}
+
+	blob, err := obtainBlob(fileName, path, mimeType, mediaType, size, hash)
+	if err != nil {
+		releaseStorage(ownerID, size)
+		return Media{}, err
+	}
+	if stored := blob.storedSize(); stored != size {
+		// Known content whose upload was dropped for its rendition
+		adjustStorage(ownerID, stored-size)
+		size = stored
+	}
+	media, err := createRecord(ownerID, fileName, blob)
+	if err != nil {
+		releaseBlob(blob.ID)
+		releaseStorage(ownerID, size)
+		return Media{}, err
+	}
+	if needsTranscode(blob) {
+		enqueueTranscode(media)
+	}
+	return media, nil
+}
+
+// obtainBlob takes a reference on the stored blob with this hash, storing the
+// file at path as that blob first if there is none. Two uploads of the same
+// new content can race to store it; the loser drops its copy and takes a
+// reference on the winner's.
+func obtainBlob(fileName, path, mimeType, mediaType string, size int64, hash string) (Blob, error) {
+	for attempt := 0; attempt < 3; attempt++ {
+		blob, err := acquireBlob(hash)
+		if err == nil {
+			return blob, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return Blob{}, err
+		}
+
+		blob, err = storeBlob(fileName, path, mimeType, mediaType, size, hash)
+		if !mongo.IsDuplicateKeyError(err) {
+			return blob, err
+		}
+	}
+	return Blob{}, errors.New("blob kept changing while it was stored")
+}
+
+// acquireBlob adds a reference to the live blob with this hash. A blob whose
+// references have dropped to zero is being deleted and isn't reused.
+func acquireBlob(hash string) (Blob, error) {
+	var blob Blob
+	err := db.GetCollection("blobs").FindOneAndUpdate(
+		context.TODO(),
+		bson.M{"sha256": hash, "refs": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"refs": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&blob)
+	return blob, err
+}
+
+// ownsContent reports whether userID has a media record of the content with
+// this hash, which is what lets them reuse it without uploading it. Uploads
+// without an owner can't be reused by anyone.
+func ownsContent(userID, hash string) (bool, error) {
+	if userID == "" || hash == "" {
+		return false, nil
+	}
+	count, err := countMedia(bson.M{"owner_id": userID, "sha256": hash})
+	return count > 0, err
+}
+
+// countMedia counts the media records that match filter. Tests replace it.
+var countMedia = func(filter bson.M) (int64, error) {
+	return db.GetCollection("media").CountDocuments(context.TODO(), filter)
+}
+
+// storeBlob writes the file at path and its thumbnails to GridFS and records
+// it as a blob with one reference
+func storeBlob(fileName, path, mimeType, mediaType string, size int64, hash string) (Blob, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Blob{}, err
+	}
+	defer f.Close()
+
+	blob := Blob{
+		Hash:      hash,
+		Size:      size,
+		MimeType:  mimeType,
+		MediaType: mediaType,
+		Refs:      1,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	describe(&blob, f, path)
+
+	bucket, err := gridfs.NewBucket(db.GetDB())
+	if err != nil {
+		return Blob{}, err
+	}
+	blob.Thumbnails = makeThumbnails(bucket, mediaType, path)
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		deleteThumbnails(bucket, blob.Thumbnails)
+		return Blob{}, err
+	}
+	blobID, err := bucket.UploadFromStream(fileName, f)
+	if err != nil {
+		deleteThumbnails(bucket, blob.Thumbnails)
+		return Blob{}, err
+	}
+
+	blob.ID = blobID.Hex()
+	if _, err := db.GetCollection("blobs").InsertOne(context.TODO(), blob); err != nil {
+		bucket.Delete(blobID)
+		deleteThumbnails(bucket, blob.Thumbnails)
+		return Blob{}, err
+	}
+	return blob, nil
+}
+
+// createRecord inserts a media record owned by ownerID for a blob the caller
+// holds a reference on
+func createRecord(ownerID, fileName string, blob Blob) (Media, error) {
+	media := Media{
+		ID:         primitive.NewObjectID().Hex(),
+		BlobID:     blob.ID,
+		OwnerID:    ownerID,
+		FileName:   fileName,
+		MimeType:   blob.MimeType,
+		MediaType:  blob.MediaType,
+		Size:       blob.storedSize(),
+		Width:      blob.Width,
+		Height:     blob.Height,
+		BlurHash:   blob.BlurHash,
+		Duration:   blob.Duration,
+		Codec:      blob.Codec,
+		Bitrate:    blob.Bitrate,
+		Waveform:   blob.Waveform,
+		Hash:       blob.Hash,
+		CreatedAt:  time.Now().UTC().Format(time.RFC3339),
+		Thumbnails: blob.Thumbnails,
+		Rendition:  blob.Rendition,
+
+		OriginalDropped: blob.OriginalDropped,
+	}
+	if _, err := db.GetCollection("media").InsertOne(context.TODO(), media); err != nil {
+		return Media{}, err
+	}
+	setThumbnailURLs(&media)
+	return media, nil
+}
+
+// releaseBlob drops a reference to a blob, deleting its files once nothing
+// refers to it. It returns the bytes that freed.
+func releaseBlob(blobID string) (int64, error) {
+	blobs := db.GetCollection("blobs")
+
+	var blob Blob
+	err := blobs.FindOneAndUpdate(
+		context.TODO(),
+		bson.M{"_id": blobID},
+		bson.M{"$inc": bson.M{"refs": -1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&blob)
+	if err != nil || blob.Refs > 0 {
+		return 0, err
+	}
+
+	result, err := blobs.DeleteOne(context.TODO(), bson.M{"_id": blobID, "refs": bson.M{"$lte": 0}})
+	if err != nil || result.DeletedCount == 0 {
+		return 0, err
+	}
+	if blob.Rendition != nil {
+		if err := deleteBlobFiles(blob.Rendition.BlobID, nil); err != nil {
+			return 0, err
+		}
+	}
+	fileID := blob.ID
+	if blob.OriginalDropped {
+		fileID = ""
+	}
+	return blob.fileBytes(), deleteBlobFiles(fileID, blob.Thumbnails)
+}
+
+// deleteBlobFiles removes a file and its thumbnails from GridFS. An empty
+// blobID removes only the thumbnails.
+func deleteBlobFiles(blobID string, thumbnails []Thumbnail) error {
+	bucket, err := gridfs.NewBucket(db.GetDB())
+	if err != nil {
+		return err
+	}
+	deleteThumbnails(bucket, thumbnails)
+
+	id, err := primitive.ObjectIDFromHex(blobID)
+	if err != nil {
+		return nil
+	}
+	if err := bucket.Delete(id); err != nil && err != gridfs.ErrFileNotFound {
+		return err
+	}
+	return nil
+}
+
+// describe fills in the dimensions and blurhash of images and videos, the
+// duration, codec and bitrate of audio and video, and the waveform of audio
+func describe(blob *Blob, f *os.File, path string) {
+	switch blob.MediaType {
+	case "image":
+		f.Seek(0, io.SeekStart)
+		if img, _, err := image.Decode(f); err == nil {
+			blob.BlurHash, blob.Width, blob.Height, _ = file.GenerateBlurHash(img)
+		}
+	case "video":
+		blob.BlurHash, blob.Width, blob.Height, _ = file.BlurHashFromVideo(path)
+		probe(blob, path)
+	case "audio":
+		probe(blob, path)
+		waveform, err := file.GenerateWaveform(path, file.WaveformBuckets)
+		if err != nil {
+			fmt.Println("Failed to extract waveform:", err)
+			return
+		}
+		blob.Waveform = waveform
+	}
+}
+
+func probe(blob *Blob, path string) {
+	result, err := file.ProbeMedia(path)
+	if err != nil {
+		fmt.Println("Failed to probe media:", err)
+		return
+	}
+	blob.Duration = result.Duration
+	blob.Codec = result.Codec
+	blob.Bitrate = result.Bitrate
+	if result.Width > 0 && result.Height > 0 {
+		// The blurhash frame is scaled down; the stream has the real size
+		blob.Width, blob.Height = result.Width, result.Height
+	}
+}
+
+// Find loads a media record, returning ErrNotFound when there is none
+func Find(mediaID string) (Media, error) {
+	return findMedia(mediaID)
+}
+
+// findMedia loads a media record. Files uploaded before the media collection
+// existed only have their GridFS entry, which is mapped onto a record.
+func findMedia(mediaID string) (Media, error) {
+	var media Media
+	err := db.GetCollection("media").FindOne(context.TODO(), bson.M{"_id": mediaID}).Decode(&media)
+	if err == nil {
+		setThumbnailURLs(&media)
+		return media, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return Media{}, err
+	}
+
+	blobID, err := primitive.ObjectIDFromHex(mediaID)
+	if err != nil {
+		return Media{}, ErrNotFound
+	}
+	var legacy struct {
+		Length     int64     `bson:"length"`
+		Filename   string    `bson:"filename"`
+		UploadDate time.Time `bson:"uploadDate"`
+		Metadata   struct {
+			MimeType  string `bson:"mime_type"`
+			MediaType string `bson:"media_type"`
+			Public    bool   `bson:"public"`
+		} `bson:"metadata"`
+	}
+	err = db.GetCollection("fs.files").FindOne(context.TODO(), bson.M{"_id": blobID}).Decode(&legacy)
+	if err == mongo.ErrNoDocuments {
+		return Media{}, ErrNotFound
+	}
+	if err != nil {
+		return Media{}, err
+	}
+
+	return Media{
+		ID:        mediaID,
+		BlobID:    mediaID,
+		FileName:  legacy.Filename,
+		MimeType:  legacy.Metadata.MimeType,
+		MediaType: legacy.Metadata.MediaType,
+		Size:      legacy.Length,
+		CreatedAt: legacy.UploadDate.UTC().Format(time.RFC3339),
+		Public:    legacy.Metadata.Public,
+		legacy:    true,
+	}, nil
+}
+
+// Delete removes a media record, and its stored bytes once no other record
+// shares them
+func Delete(mediaID string) error {
+	media, err := findMedia(mediaID)
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = deleteMedia(media)
+	return err
+}
+
+// deleteMedia removes a loaded media record and returns how many stored bytes
+// that freed, which is zero while other records still share the blob
+func deleteMedia(media Media) (int64, error) {
+	if media.legacy {
+		return media.Size, deleteBlobFiles(media.BlobID, nil)
+	}
+
+	result, err := db.GetCollection("media").DeleteOne(context.TODO(), bson.M{"_id": media.ID})
+	if err != nil || result.DeletedCount == 0 {
+		// Someone else deleted it first and released its blob
+		return 0, err
+	}
+	releaseStorage(media.OwnerID, media.Size)
+
+	count, err := db.GetCollection("blobs").CountDocuments(context.TODO(), bson.M{"_id": media.BlobID})
+	if err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		// Stored before deduplication, so the record was the blob's only user
+		return media.Size + thumbnailBytes(media.Thumbnails), deleteBlobFiles(media.BlobID, media.Thumbnails)
+	}
+	return releaseBlob(media.BlobID)
+}
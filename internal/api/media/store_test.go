@@ -0,0 +1,47 @@
+package media
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestOwnsContent(t *testing.T) {
+	records := []Media{
+		{OwnerID: "alice", Hash: "aaa"},
+		{OwnerID: "bob", Hash: "bbb"},
+		{OwnerID: "", Hash: "ccc"}, // uploaded through the old routes
+	}
+	original := countMedia
+	t.Cleanup(func() { countMedia = original })
+	countMedia = func(filter bson.M) (int64, error) {
+		var count int64
+		for _, media := range records {
+			if filter["owner_id"] == media.OwnerID && filter["sha256"] == media.Hash {
+				count++
+			}
+		}
+		return count, nil
+	}
+
+	tests := []struct {
+		name   string
+		userID string
+		hash   string
+		want   bool
+	}{
+		{"own upload", "alice", "aaa", true},
+		{"someone else's upload", "alice", "bbb", false},
+		{"unknown content", "alice", "ddd", false},
+		{"ownerless upload", "", "ccc", false},
+		{"no hash", "alice", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ownsContent(tt.userID, tt.hash)
+			if err != nil || got != tt.want {
+				t.Errorf("ownsContent(%q, %q) = %v, %v; want %v", tt.userID, tt.hash, got, err, tt.want)
+			}
+		})
+	}
+}
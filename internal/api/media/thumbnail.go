@@ -0,0 +1,146 @@
+package media
+
+import (
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"gochat_server/internal/api/file"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+)
+
+// thumbnailSizes are the boxes, in pixels, thumbnails are fitted in
+var thumbnailSizes = []int{96, 320, 800}
+
+const defaultThumbnailSize = 320
+
+// makeThumbnails renders and stores a thumbnail at each size for the image or
+// video at path. A size that fails to render is skipped.
+func makeThumbnails(bucket *gridfs.Bucket, mediaType, path string) []Thumbnail {
+	if mediaType != "image" && mediaType != "video" {
+		return nil
+	}
+
+	var thumbnails []Thumbnail
+	for _, size := range thumbnailSizes {
+		thumbnail, err := storeThumbnail(bucket, mediaType, path, size)
+		if err != nil {
+			fmt.Printf("Failed to create %dpx thumbnail: %v\n", size, err)
+			continue
+		}
+		thumbnails = append(thumbnails, thumbnail)
+	}
+	return thumbnails
+}
+
+func storeThumbnail(bucket *gridfs.Bucket, mediaType, path string, size int) (Thumbnail, error) {
+	thumbPath, err := file.GenerateThumbnail(path, size, mediaType == "video")
+	if err != nil {
+		return Thumbnail{}, err
+	}
+	defer os.Remove(thumbPath)
+
+	f, err := os.Open(thumbPath)
+	if err != nil {
+		return Thumbnail{}, err
+	}
+	defer f.Close()
+
+	config, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return Thumbnail{}, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return Thumbnail{}, err
+	}
+	f.Seek(0, io.SeekStart)
+
+	blobID, err := bucket.UploadFromStream(fmt.Sprintf("thumb-%d.jpg", size), f)
+	if err != nil {
+		return Thumbnail{}, err
+	}
+
+	return Thumbnail{
+		Size:   size,
+		BlobID: blobID.Hex(),
+		Width:  config.Width,
+		Height: config.Height,
+		Bytes:  info.Size(),
+	}, nil
+}
+
+// setThumbnailURLs fills in where each of the media's thumbnails is served
+func setThumbnailURLs(media *Media) {
+	for i := range media.Thumbnails {
+		media.Thumbnails[i].URL = fmt.Sprintf("/file/thumbnail/%s?size=%d", media.ID, media.Thumbnails[i].Size)
+	}
+}
+
+// deleteThumbnails removes the stored thumbnail files
+func deleteThumbnails(bucket *gridfs.Bucket, thumbnails []Thumbnail) {
+	for _, thumbnail := range thumbnails {
+		if blobID, err := primitive.ObjectIDFromHex(thumbnail.BlobID); err == nil {
+			bucket.Delete(blobID)
+		}
+	}
+}
+
+func thumbnailBytes(thumbnails []Thumbnail) int64 {
+	var total int64
+	for _, thumbnail := range thumbnails {
+		total += thumbnail.Bytes
+	}
+	return total
+}
+
+// pickThumbnail returns the smallest thumbnail at least size pixels, or the
+// largest one there is. Thumbnails are stored smallest first.
+func pickThumbnail(thumbnails []Thumbnail, size int) Thumbnail {
+	for _, thumbnail := range thumbnails {
+		if thumbnail.Size >= size {
+			return thumbnail
+		}
+	}
+	return thumbnails[len(thumbnails)-1]
+}
+
+// ServeThumbnail serves the thumbnail of a media closest to ?size= (default
+// 320) pixels
+func ServeThumbnail(c *gin.Context) {
+	media, err := findMedia(c.Param("file_id"))
+	if err != nil || len(media.Thumbnails) == 0 || !authorize(c, media) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Thumbnail not found"})
+		return
+	}
+
+	size, err := strconv.Atoi(c.DefaultQuery("size", strconv.Itoa(defaultThumbnailSize)))
+	if err != nil || size <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid size"})
+		return
+	}
+	thumbnail := pickThumbnail(media.Thumbnails, size)
+
+	blob, err := openBlobReader(c.Request.Context(), thumbnail.BlobID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Thumbnail not found"})
+		return
+	}
+	defer blob.Close()
+
+	c.Header("Content-Type", "image/jpeg")
+	c.Header("ETag", fmt.Sprintf(`"%s-%d"`, thumbnail.BlobID, thumbnail.Size))
+	// Access to the media can be revoked, so clients revalidate first
+	c.Header("Cache-Control", "private, no-cache")
+
+	modTime, _ := time.Parse(time.RFC3339, media.CreatedAt)
+	http.ServeContent(c.Writer, c.Request, "", modTime, blob)
+}
@@ -0,0 +1,29 @@
+package media
+
+import "testing"
+
+func TestPickThumbnail(t *testing.T) {
+	thumbnails := []Thumbnail{{Size: 96}, {Size: 320}, {Size: 800}}
+
+	tests := []struct {
+		size int
+		want int
+	}{
+		{1, 96},
+		{96, 96},
+		{97, 320},
+		{320, 320},
+		{500, 800},
+		{800, 800},
+		{2000, 800},
+	}
+	for _, tt := range tests {
+		if got := pickThumbnail(thumbnails, tt.size); got.Size != tt.want {
+			t.Errorf("pickThumbnail(%d) = %d, want %d", tt.size, got.Size, tt.want)
+		}
+	}
+
+	if got := pickThumbnail([]Thumbnail{{Size: 320}}, 96); got.Size != 320 {
+		t.Errorf("pickThumbnail with one thumbnail = %d, want 320", got.Size)
+	}
+}
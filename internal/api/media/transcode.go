@@ -0,0 +1,359 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"gochat_server/config"
+	"gochat_server/internal/api/file"
+	"gochat_server/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	jobTranscode = "transcode"
+
+	jobQueued  = "queued"
+	jobRunning = "running"
+	jobDone    = "done"
+	jobFailed  = "failed"
+
+	maxJobAttempts = 3
+
+	// progressStep is how many percent a transcode moves between progress
+	// events, so the uploader isn't sent one per ffmpeg status line
+	progressStep = 10
+)
+
+var errBlobGone = errors.New("media was deleted")
+
+// workerID identifies this server process when it leases jobs
+var workerID = func() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), primitive.NewObjectID().Hex())
+}()
+
+// needsTranscode reports whether a freshly stored blob is a video phones may
+// not play, or play at a needless resolution. A blob with more than one
+// reference was already there and has had its job queued.
+func needsTranscode(blob Blob) bool {
+	if config.Cfg.TranscodeWorkers <= 0 || blob.MediaType != "video" || blob.Rendition != nil || blob.Refs != 1 {
+		return false
+	}
+	mobileFriendly := blob.MimeType == "video/mp4" && blob.Codec == "h264" &&
+		blob.Width > 0 && min(blob.Width, blob.Height) <= config.Cfg.TranscodeMaxSide
+	return !mobileFriendly
+}
+
+// enqueueTranscode queues a transcode of the media's blob
+func enqueueTranscode(media Media) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	job := Job{
+		ID:        primitive.NewObjectID().Hex(),
+		Type:      jobTranscode,
+		MediaID:   media.ID,
+		BlobID:    media.BlobID,
+		OwnerID:   media.OwnerID,
+		Status:    jobQueued,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	_, err := db.GetCollection("jobs").InsertOne(context.TODO(), job)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		fmt.Println("Failed to queue transcode:", err)
+	}
+}
+
+// RunTranscoder runs the configured number of transcode workers until the
+// process exits
+func RunTranscoder() {
+	var wg sync.WaitGroup
+	for i := 0; i < config.Cfg.TranscodeWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			transcodeWorker()
+		}()
+	}
+	wg.Wait()
+}
+
+func transcodeWorker() {
+	for {
+		job, err := claimJob(jobTranscode)
+		if err != nil {
+			if err != mongo.ErrNoDocuments {
+				fmt.Println("Error claiming transcode job:", err)
+			}
+			time.Sleep(config.Cfg.TranscodePollInterval)
+			continue
+		}
+		runTranscode(job)
+	}
+}
+
+// claimJob leases the oldest queued job of a type, or a running one whose
+// worker let its lease run out
+func claimJob(jobType string) (Job, error) {
+	now := time.Now().UTC()
+	var job Job
+	err := db.GetCollection("jobs").FindOneAndUpdate(
+		context.TODO(),
+		bson.M{
+			"type": jobType,
+			"$or": bson.A{
+				bson.M{"status": jobQueued},
+				bson.M{"status": jobRunning, "lease_until": bson.M{"$lt": now.Format(time.RFC3339)}},
+			},
+		},
+		bson.M{
+			"$set": bson.M{
+				"status":      jobRunning,
+				"lease_owner": workerID,
+				"lease_until": now.Add(config.Cfg.TranscodeLease).Format(time.RFC3339),
+				"updated_at":  now.Format(time.RFC3339),
+			},
+			"$inc": bson.M{"attempts": 1},
+		},
+		options.FindOneAndUpdate().
+			SetSort(bson.D{{Key: "created_at", Value: 1}}).
+			SetReturnDocument(options.After),
+	).Decode(&job)
+	return job, err
+}
+
+// updateJob sets fields on a job this worker holds, renewing its lease
+func updateJob(jobID string, set bson.M) {
+	now := time.Now().UTC()
+	set["updated_at"] = now.Format(time.RFC3339)
+	if set["status"] == jobRunning {
+		set["lease_until"] = now.Add(config.Cfg.TranscodeLease).Format(time.RFC3339)
+	}
+	_, err := db.GetCollection("jobs").UpdateOne(
+		context.TODO(),
+		bson.M{"_id": jobID, "lease_owner": workerID},
+		bson.M{"$set": set},
+	)
+	if err != nil {
+		fmt.Println("Failed to update job:", err)
+	}
+}
+
+// keepLeased renews a job's lease until stop is called, so a transcode that
+// goes a long while between progress events isn't claimed by another worker
+func keepLeased(jobID string) (stop func()) {
+	interval := config.Cfg.TranscodeLease / 3
+	if interval <= 0 {
+		return func() {}
+	}
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				updateJob(jobID, bson.M{"status": jobRunning})
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+func runTranscode(job Job) {
+	stop := keepLeased(job.ID)
+	rendition, err := transcode(job)
+	if err == nil {
+		err = attachRendition(job, rendition)
+	}
+	stop()
+
+	switch {
+	case err == nil:
+		updateJob(job.ID, bson.M{"status": jobDone, "progress": 100})
+		notify(job.OwnerID, map[string]interface{}{
+			"type": "media_transcoded",
+			"data": map[string]interface{}{"media_id": job.MediaID, "rendition": rendition},
+		})
+	case errors.Is(err, errBlobGone) || job.Attempts >= maxJobAttempts:
+		fmt.Println("Transcode failed:", err)
+		updateJob(job.ID, bson.M{"status": jobFailed, "error": err.Error()})
+		notify(job.OwnerID, map[string]interface{}{
+			"type": "media_transcode_failed",
+			"data": map[string]interface{}{"media_id": job.MediaID},
+		})
+	default:
+		fmt.Println("Transcode failed, will retry:", err)
+		updateJob(job.ID, bson.M{"status": jobQueued, "progress": 0, "error": err.Error()})
+	}
+}
+
+// transcode renders the job's blob as an H.264/AAC MP4 and stores it in
+// GridFS, publishing progress to the uploader on the way
+func transcode(job Job) (Rendition, error) {
+	var blob Blob
+	err := db.GetCollection("blobs").FindOne(context.TODO(), bson.M{"_id": job.BlobID, "refs": bson.M{"$gt": 0}}).Decode(&blob)
+	if err == mongo.ErrNoDocuments {
+		return Rendition{}, errBlobGone
+	}
+	if err != nil {
+		return Rendition{}, err
+	}
+
+	srcPath, err := downloadBlob(blob.ID)
+	if err != nil {
+		return Rendition{}, err
+	}
+	defer os.Remove(srcPath)
+
+	reported := 0
+	outPath, err := file.TranscodeVideo(srcPath, config.Cfg.TranscodeMaxSide, blob.Duration, func(progress int) {
+		if progress < reported+progressStep || progress == 100 {
+			return
+		}
+		reported = progress - progress%progressStep
+		updateJob(job.ID, bson.M{"status": jobRunning, "progress": reported})
+		notify(job.OwnerID, map[string]interface{}{
+			"type": "media_transcode_progress",
+			"data": map[string]interface{}{"media_id": job.MediaID, "progress": reported},
+		})
+	})
+	if err != nil {
+		return Rendition{}, err
+	}
+	defer os.Remove(outPath)
+
+	probe, err := file.ProbeMedia(outPath)
+	if err != nil {
+		return Rendition{}, err
+	}
+	out, err := os.Open(outPath)
+	if err != nil {
+		return Rendition{}, err
+	}
+	defer out.Close()
+	info, err := out.Stat()
+	if err != nil {
+		return Rendition{}, err
+	}
+
+	bucket, err := gridfs.NewBucket(db.GetDB())
+	if err != nil {
+		return Rendition{}, err
+	}
+	fileID, err := bucket.UploadFromStream(job.MediaID+".mp4", out)
+	if err != nil {
+		return Rendition{}, err
+	}
+
+	return Rendition{
+		BlobID:   fileID.Hex(),
+		MimeType: "video/mp4",
+		Size:     info.Size(),
+		Width:    probe.Width,
+		Height:   probe.Height,
+		Codec:    probe.Codec,
+		Bitrate:  probe.Bitrate,
+	}, nil
+}
+
+// attachRendition records a rendition on its blob and on every media record
+// sharing the blob, then drops the original when it isn't to be kept. The
+// blob is updated first so records created in between copy the rendition.
+func attachRendition(job Job, rendition Rendition) error {
+	dropOriginal := !config.Cfg.TranscodeKeepOriginal
+	result, err := db.GetCollection("blobs").UpdateOne(
+		context.TODO(),
+		bson.M{"_id": job.BlobID, "refs": bson.M{"$gt": 0}},
+		bson.M{"$set": bson.M{"rendition": rendition, "original_dropped": dropOriginal}},
+	)
+	if err == nil && result.MatchedCount == 0 {
+		err = errBlobGone
+	}
+	if err != nil {
+		deleteBlobFiles(rendition.BlobID, nil)
+		return err
+	}
+
+	if _, err := db.GetCollection("media").UpdateMany(
+		context.TODO(),
+		bson.M{"blob_id": job.BlobID},
+		bson.M{"$set": bson.M{"rendition": rendition}},
+	); err != nil {
+		return err
+	}
+
+	if dropOriginal {
+		if err := chargeRendition(job.BlobID, rendition); err != nil {
+			return err
+		}
+		if err := deleteBlobFiles(job.BlobID, nil); err != nil {
+			fmt.Println("Failed to drop original video:", err)
+		}
+	}
+	return nil
+}
+
+// chargeRendition marks the records of a blob whose upload is being dropped,
+// and charges their owners for the rendition instead of the upload. Each
+// record is settled on its own so a retried job doesn't charge it twice.
+func chargeRendition(blobID string, rendition Rendition) error {
+	records := db.GetCollection("media")
+	cursor, err := records.Find(context.TODO(), bson.M{"blob_id": blobID, "original_dropped": bson.M{"$ne": true}})
+	if err != nil {
+		return err
+	}
+	var batch []Media
+	if err := cursor.All(context.TODO(), &batch); err != nil {
+		return err
+	}
+
+	for _, media := range batch {
+		result, err := records.UpdateOne(
+			context.TODO(),
+			bson.M{"_id": media.ID, "original_dropped": bson.M{"$ne": true}},
+			bson.M{"$set": bson.M{"original_dropped": true, "size": rendition.Size}},
+		)
+		if err != nil {
+			return err
+		}
+		if result.ModifiedCount > 0 {
+			adjustStorage(media.OwnerID, rendition.Size-media.Size)
+		}
+	}
+	return nil
+}
+
+// downloadBlob copies a GridFS file to a temporary file. The caller removes
+// it.
+func downloadBlob(blobID string) (string, error) {
+	blob, err := openBlobReader(context.TODO(), blobID)
+	if err != nil {
+		return "", err
+	}
+	defer blob.Close()
+
+	tmp, err := os.CreateTemp("", "media-*")
+	if err != nil {
+		return "", err
+	}
+	defer tmp.Close()
+
+	if _, err := io.Copy(tmp, blob); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
@@ -0,0 +1,336 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"gochat_server/config"
+	"gochat_server/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxChunkSize caps a single PATCH so each chunk fits in one document
+const maxChunkSize = 8 << 20
+
+const (
+	uploadOpen       = "open"
+	uploadFinalizing = "finalizing"
+)
+
+// Resumable uploads: create a session, PATCH the bytes in chunks carrying an
+// Upload-Offset header, ask for the current offset with HEAD after a dropped
+// connection, and finalize once every byte has arrived.
+
+// CreateUpload starts a resumable upload session
+func CreateUpload(c *gin.Context) {
+	userID := callerID(c)
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing user_id"})
+		return
+	}
+
+	var request CreateUploadRequest
+	if err := c.ShouldBindJSON(&request); err != nil || request.Size <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	// The type isn't known until the bytes arrive, so only the largest limit
+	// applies here; finalize checks the limit for the actual type
+	if request.Size > maxUploadSize() {
+		writeUploadError(c, ErrTooLarge)
+		return
+	}
+	if !hasRoomFor(userID, request.Size) {
+		writeUploadError(c, ErrQuotaExceeded)
+		return
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	session := UploadSession{
+		ID:        primitive.NewObjectID().Hex(),
+		OwnerID:   userID,
+		FileName:  request.FileName,
+		Size:      request.Size,
+		Status:    uploadOpen,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if _, err := db.GetCollection("upload_sessions").InsertOne(context.TODO(), session); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload"})
+		return
+	}
+
+	c.Header("Location", "/api/media/uploads/"+session.ID)
+	c.JSON(http.StatusCreated, session)
+}
+
+// GetUploadOffset reports how many bytes of an upload the server has, in the
+// Upload-Offset header
+func GetUploadOffset(c *gin.Context) {
+	session, err := findUploadSession(c.Param("id"), callerID(c))
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(session.Size, 10))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+}
+
+// UploadChunk appends the request body to an upload. Upload-Offset must equal
+// the offset the server has; a mismatch answers 409 with the current offset
+// so the client can resume from there.
+func UploadChunk(c *gin.Context) {
+	session, err := findUploadSession(c.Param("id"), callerID(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing or invalid Upload-Offset header"})
+		return
+	}
+	// Refuse a chunk that can't be stored before reading it
+	if status, reason := session.checkChunk(offset, 1); status != 0 {
+		writeChunkError(c, session, status, reason)
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxChunkSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error reading chunk"})
+		return
+	}
+	if status, reason := session.checkChunk(offset, len(data)); status != 0 {
+		writeChunkError(c, session, status, reason)
+		return
+	}
+
+	// The unique (session_id, offset) index stops two requests racing for the
+	// same offset; the conditional update then moves the offset on
+	chunks := db.GetCollection("upload_chunks")
+	_, err = chunks.InsertOne(context.TODO(), bson.M{"session_id": session.ID, "offset": offset, "data": data})
+	if mongo.IsDuplicateKeyError(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A chunk at this offset was already received"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store chunk"})
+		return
+	}
+
+	newOffset := offset + int64(len(data))
+	result, err := db.GetCollection("upload_sessions").UpdateOne(
+		context.TODO(),
+		bson.M{"_id": session.ID, "offset": offset, "status": uploadOpen},
+		bson.M{"$set": bson.M{"offset": newOffset, "updated_at": time.Now().UTC().Format(time.RFC3339)}},
+	)
+	if err != nil || result.MatchedCount == 0 {
+		chunks.DeleteOne(context.TODO(), bson.M{"session_id": session.ID, "offset": offset})
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload changed while the chunk was stored"})
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(newOffset, 10))
+	c.Status(http.StatusNoContent)
+}
+
+// FinalizeUpload assembles a complete upload into GridFS, runs it through the
+// same metadata pipeline as a direct upload, and returns the media record
+func FinalizeUpload(c *gin.Context) {
+	userID := callerID(c)
+	sessions := db.GetCollection("upload_sessions")
+
+	var session UploadSession
+	err := sessions.FindOne(context.TODO(), bson.M{"_id": c.Param("id"), "owner_id": userID}).Decode(&session)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+		return
+	}
+	if status, reason := session.checkFinalize(); status != 0 {
+		c.JSON(status, gin.H{"error": reason, "offset": session.Offset, "size": session.Size})
+		return
+	}
+
+	// Claim the session so a retried finalize doesn't ingest it twice
+	result, err := sessions.UpdateOne(
+		context.TODO(),
+		bson.M{"_id": session.ID, "status": uploadOpen},
+		bson.M{"$set": bson.M{"status": uploadFinalizing, "updated_at": time.Now().UTC().Format(time.RFC3339)}},
+	)
+	if err != nil || result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload is already being finalized"})
+		return
+	}
+
+	media, err := assembleUpload(session)
+	if isRejection(err) {
+		removeUploadSession(session.ID)
+		writeUploadError(c, err)
+		return
+	}
+	if err != nil {
+		fmt.Println("Failed to finalize upload:", err)
+		sessions.UpdateOne(context.TODO(), bson.M{"_id": session.ID}, bson.M{"$set": bson.M{"status": uploadOpen}})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to finalize upload"})
+		return
+	}
+
+	removeUploadSession(session.ID)
+	c.JSON(http.StatusOK, media)
+}
+
+// CancelUpload abandons an upload and discards its chunks
+func CancelUpload(c *gin.Context) {
+	session, err := findUploadSession(c.Param("id"), callerID(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+		return
+	}
+
+	if err := removeUploadSession(session.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel upload"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Upload cancelled"})
+}
+
+// RunUploadJanitor periodically removes upload sessions that have been idle
+// for longer than the configured TTL
+func RunUploadJanitor() {
+	ticker := time.NewTicker(config.Cfg.UploadCleanupEvery)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		removeStaleUploads()
+	}
+}
+
+func removeStaleUploads() {
+	cutoff := time.Now().UTC().Add(-config.Cfg.UploadSessionTTL).Format(time.RFC3339)
+
+	cursor, err := db.GetCollection("upload_sessions").Find(context.TODO(), bson.M{"updated_at": bson.M{"$lt": cutoff}})
+	if err != nil {
+		fmt.Println("Error finding stale uploads:", err)
+		return
+	}
+	var stale []UploadSession
+	if err := cursor.All(context.TODO(), &stale); err != nil {
+		fmt.Println("Error decoding stale uploads:", err)
+		return
+	}
+
+	for _, session := range stale {
+		if err := removeUploadSession(session.ID); err != nil {
+			fmt.Println("Failed to remove stale upload:", err)
+		}
+	}
+}
+
+// assembleUpload streams a session's chunks, in offset order, through the
+// spool and into ingest
+func assembleUpload(session UploadSession) (Media, error) {
+	cursor, err := db.GetCollection("upload_chunks").Find(
+		context.TODO(),
+		bson.M{"session_id": session.ID},
+		options.Find().SetSort(bson.D{{Key: "offset", Value: 1}}),
+	)
+	if err != nil {
+		return Media{}, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		defer cursor.Close(context.TODO())
+		for cursor.Next(context.TODO()) {
+			var chunk struct {
+				Data []byte `bson:"data"`
+			}
+			if err := cursor.Decode(&chunk); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			if _, err := pw.Write(chunk.Data); err != nil {
+				return
+			}
+		}
+		pw.CloseWithError(cursor.Err())
+	}()
+
+	path, size, hash, err := spool(pr)
+	pr.Close()
+	if err != nil {
+		return Media{}, err
+	}
+	defer os.Remove(path)
+	if size != session.Size {
+		return Media{}, fmt.Errorf("assembled %d bytes, expected %d", size, session.Size)
+	}
+
+	return ingest(session.OwnerID, session.FileName, path, size, hash)
+}
+
+// checkChunk checks a chunk of length bytes sent at offset against the
+// session, returning the status to refuse it with and why, or 0 when it
+// carries on the upload
+func (s UploadSession) checkChunk(offset int64, length int) (int, string) {
+	switch {
+	case s.Status != uploadOpen:
+		return http.StatusConflict, "Upload is being finalized"
+	case offset != s.Offset:
+		return http.StatusConflict, "Upload-Offset does not match the upload"
+	case length > maxChunkSize:
+		return http.StatusRequestEntityTooLarge, fmt.Sprintf("Chunks can be at most %d bytes", maxChunkSize)
+	case length == 0 || offset+int64(length) > s.Size:
+		return http.StatusBadRequest, "Chunk is empty or runs past the upload length"
+	}
+	return 0, ""
+}
+
+// checkFinalize returns the status to refuse finalizing the session with and
+// why, or 0 when every byte has arrived
+func (s UploadSession) checkFinalize() (int, string) {
+	switch {
+	case s.Status != uploadOpen:
+		return http.StatusConflict, "Upload is already being finalized"
+	case s.Offset != s.Size:
+		return http.StatusConflict, "Upload is incomplete"
+	}
+	return 0, ""
+}
+
+// writeChunkError refuses a chunk, telling the client where the upload
+// stands so it can resume from there
+func writeChunkError(c *gin.Context, session UploadSession, status int, reason string) {
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	c.JSON(status, gin.H{"error": reason, "offset": session.Offset})
+}
+
+func findUploadSession(uploadID, ownerID string) (UploadSession, error) {
+	var session UploadSession
+	err := db.GetCollection("upload_sessions").FindOne(context.TODO(), bson.M{"_id": uploadID, "owner_id": ownerID}).Decode(&session)
+	return session, err
+}
+
+func removeUploadSession(uploadID string) error {
+	if _, err := db.GetCollection("upload_chunks").DeleteMany(context.TODO(), bson.M{"session_id": uploadID}); err != nil {
+		return err
+	}
+	_, err := db.GetCollection("upload_sessions").DeleteOne(context.TODO(), bson.M{"_id": uploadID})
+	return err
+}
@@ -0,0 +1,57 @@
+package media
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCheckChunk(t *testing.T) {
+	session := UploadSession{Size: 100, Offset: 40, Status: uploadOpen}
+	finalizing := session
+	finalizing.Status = uploadFinalizing
+	large := UploadSession{Size: 2 * maxChunkSize, Status: uploadOpen}
+
+	tests := []struct {
+		name    string
+		session UploadSession
+		offset  int64
+		length  int
+		want    int
+	}{
+		{"next chunk", session, 40, 30, 0},
+		{"last chunk", session, 40, 60, 0},
+		{"finalizing", finalizing, 40, 30, http.StatusConflict},
+		{"behind", session, 10, 30, http.StatusConflict},
+		{"ahead", session, 70, 30, http.StatusConflict},
+		{"empty", session, 40, 0, http.StatusBadRequest},
+		{"past the end", session, 40, 61, http.StatusBadRequest},
+		{"largest chunk", large, 0, maxChunkSize, 0},
+		{"too large", large, 0, maxChunkSize + 1, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, reason := tt.session.checkChunk(tt.offset, tt.length); got != tt.want {
+				t.Errorf("checkChunk(%d, %d) = %d (%s), want %d", tt.offset, tt.length, got, reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckFinalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		session UploadSession
+		want    int
+	}{
+		{"complete", UploadSession{Size: 100, Offset: 100, Status: uploadOpen}, 0},
+		{"incomplete", UploadSession{Size: 100, Offset: 99, Status: uploadOpen}, http.StatusConflict},
+		{"already finalizing", UploadSession{Size: 100, Offset: 100, Status: uploadFinalizing}, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, reason := tt.session.checkFinalize(); got != tt.want {
+				t.Errorf("checkFinalize() = %d (%s), want %d", got, reason, tt.want)
+			}
+		})
+	}
+}
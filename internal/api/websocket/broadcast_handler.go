@@ -0,0 +1,103 @@
+package websocket
+
+import (
+	"fmt"
+	"time"
+
+	"gochat_server/internal/api/broadcast"
+	"gochat_server/internal/api/media"
+	"gochat_server/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// handleBroadcastMessage expands a message sent to a broadcast list into a
+// separate 1:1 message per recipient, each going through the normal routing
+// and offline path. Replies therefore land in the sender's 1:1 chat with that
+// recipient, and recipients never learn who else was on the list.
+func handleBroadcastMessage(userId string, incmsg IncomingMessage) {
+	var request BroadcastMessageRequest
+	if err := utils.BindData(incmsg.Data, &request); err != nil {
+		fmt.Println("Invalid broadcast payload:", err)
+		return
+	}
+	message := request.Message
+	message.SenderId = userId
+
+	if message.Id == "" {
+		sendErrorAck(userId, message, "missing message id")
+		return
+	}
+
+	if message.MediaId != "" && !media.CanAccess(userId, message.MediaId) {
+		sendErrorAck(userId, message, "media not found")
+		return
+	}
+
+	list, err := broadcast.FindList(request.ListId, userId)
+	if err != nil {
+		sendErrorAck(userId, message, "broadcast list not found")
+		return
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	record := broadcast.Message{
+		ID:         message.Id,
+		ListID:     list.ID,
+		SenderID:   userId,
+		Content:    message.Content,
+		Type:       message.Type,
+		CreatedAt:  now,
+		Recipients: []broadcast.Recipient{},
+	}
+
+	var copies []Message
+	for _, recipientId := range list.Recipients {
+		dm := message
+		dm.Id = primitive.NewObjectID().Hex()
+		dm.ReceiverId = recipientId
+		dm.ChatId = directChatId(userId, recipientId)
+		dm.GroupId = ""
+		dm.Mentions = nil
+		dm.BroadcastId = message.Id
+		copies = append(copies, dm)
+
+		record.Recipients = append(record.Recipients, broadcast.Recipient{
+			UserID:    recipientId,
+			MessageID: dm.Id,
+			Status:    broadcast.StatusSent,
+			UpdatedAt: now,
+		})
+	}
+
+	// Record the copies first so delivery acks always find them
+	if err := broadcast.RecordMessage(record); err != nil {
+		fmt.Println("Failed to record broadcast:", err)
+		sendErrorAck(userId, message, "failed to send broadcast")
+		return
+	}
+
+	for _, dm := range copies {
+		accepted, isNew := acceptMessage(dm)
+		forwardMessage(userId, accepted, isNew)
+	}
+
+	sendJsonMessage(userId, map[string]interface{}{
+		"type": "ack_broadcast",
+		"data": BroadcastAcknowledgment{
+			BroadcastId: message.Id,
+			ListId:      list.ID,
+			Recipients:  len(copies),
+			ServerTS:    now,
+		},
+	})
+}
+
+// directChatId names the 1:1 chat between two users the same way whichever
+// of them is sending: both IDs, in order, joined with "_"
+func directChatId(userA, userB string) string {
+	if userB < userA {
+		userA, userB = userB, userA
+	}
+	return userA + "_" + userB
+}
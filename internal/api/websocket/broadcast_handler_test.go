@@ -0,0 +1,12 @@
+package websocket
+
+import "testing"
+
+func TestDirectChatId(t *testing.T) {
+	if got := directChatId("u2", "u1"); got != "u1_u2" {
+		t.Errorf("directChatId(u2, u1) = %q, want u1_u2", got)
+	}
+	if directChatId("u1", "u2") != directChatId("u2", "u1") {
+		t.Error("directChatId depends on who is sending")
+	}
+}
@@ -0,0 +1,101 @@
+package websocket
+
+import (
+	"fmt"
+
+	"gochat_server/internal/api/channel"
+	"gochat_server/internal/api/media"
+	"gochat_server/internal/utils"
+)
+
+// channelFanoutBatch bounds the $in list used to find online followers
+const channelFanoutBatch = 1000
+
+// handleChannelPost publishes a post from a channel admin. Only followers who
+// are online get it pushed; nobody gets an offline copy, and everyone else
+// picks it up from the channel feed.
+func handleChannelPost(userId string, incmsg IncomingMessage) {
+	var request ChannelPostRequest
+	if err := utils.BindData(incmsg.Data, &request); err != nil {
+		fmt.Println("Invalid channel post payload:", err)
+		return
+	}
+
+	ch, err := channel.FindChannel(request.ChannelId)
+	if err != nil {
+		sendChannelPostError(userId, request, "channel not found")
+		return
+	}
+	if !ch.IsAdmin(userId) {
+		sendChannelPostError(userId, request, "only channel admins can post")
+		return
+	}
+
+	if request.MediaId != "" && !media.IsOwner(userId, request.MediaId) {
+		sendChannelPostError(userId, request, "media not found")
+		return
+	}
+
+	post, err := channel.CreatePost(ch, userId, channel.Post{
+		Content: request.Content,
+		Type:    request.Type,
+		MediaID: request.MediaId,
+	})
+	if err != nil {
+		fmt.Println("Failed to store channel post:", err)
+		sendChannelPostError(userId, request, "failed to publish post")
+		return
+	}
+
+	sendJsonMessage(userId, map[string]interface{}{
+		"type": "ack_channel_post",
+		"data": map[string]interface{}{"client_id": request.ClientId, "post": post},
+	})
+
+	go fanOutChannelPost(userId, post)
+}
+
+// fanOutChannelPost pushes a post to the channel's followers who are online
+func fanOutChannelPost(authorId string, post channel.Post) {
+	online := onlineUserIds()
+	event := map[string]interface{}{"type": "channel_post", "data": post}
+
+	for start := 0; start < len(online); start += channelFanoutBatch {
+		end := min(start+channelFanoutBatch, len(online))
+		followers, err := channel.FollowersAmong(post.ChannelID, online[start:end])
+		if err != nil {
+			fmt.Println("Error finding online channel followers:", err)
+			continue
+		}
+		for _, followerId := range followers {
+			if followerId == authorId {
+				continue
+			}
+			if err := sendJsonMessage(followerId, event); err != nil {
+				fmt.Println("Error sending channel post to", followerId+":", err)
+			}
+		}
+	}
+}
+
+func sendChannelPostError(userId string, request ChannelPostRequest, reason string) {
+	sendJsonMessage(userId, map[string]interface{}{
+		"type": "ack_error",
+		"data": map[string]interface{}{
+			"client_id":  request.ClientId,
+			"channel_id": request.ChannelId,
+			"error":      reason,
+		},
+	})
+}
+
+func onlineUserIds() []string {
+	onlineUsersMutex.RLock()
+	defer onlineUsersMutex.RUnlock()
+
+	ids := make([]string, 0, len(onlineUsers))
+	for userId := range onlineUsers {
+		ids = append(ids, userId)
+	}
+	return ids
+}
@@ -0,0 +1,51 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+
+	"gochat_server/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureIndexes creates the indexes the messaging collections rely on
+func EnsureIndexes() {
+	indexes := map[string][]mongo.IndexModel{
+		"scheduled_messages": {
+			{Keys: bson.D{{Key: "status", Value: 1}, {Key: "send_at", Value: 1}}},
+			{Keys: bson.D{{Key: "sender_id", Value: 1}, {Key: "send_at", Value: 1}}},
+		},
+		"messages": {
+			{Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "mentions", Value: 1}, {Key: "server_ts", Value: -1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "media_id", Value: 1}}, Options: options.Index().SetSparse(true)},
+		},
+		"offline_messages": {
+			{Keys: bson.D{{Key: "message.data.media_id", Value: 1}}, Options: options.Index().SetSparse(true)},
+		},
+		"chat_mentions": {
+			{
+				Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "chat_id", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+		},
+		"pinned_messages": {
+			{Keys: bson.D{{Key: "chat_id", Value: 1}, {Key: "pinned_at", Value: -1}}},
+		},
+		"starred_messages": {
+			{
+				Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "message_id", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "starred_at", Value: -1}}},
+		},
+	}
+
+	for collection, models := range indexes {
+		if _, err := db.GetCollection(collection).Indexes().CreateMany(context.TODO(), models); err != nil {
+			fmt.Printf("Failed to create indexes on %s: %v\n", collection, err)
+		}
+	}
+}
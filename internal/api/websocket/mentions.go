@@ -0,0 +1,96 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+
+	"gochat_server/internal/api/fcm"
+	"gochat_server/internal/api/group"
+	"gochat_server/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mentionAll mentions every member of the group; only admins may use it
+const mentionAll = "@all"
+
+// resolveMentions keeps the mentions of a group message that name other
+// members of the group, expanding @all for admins. Mentions outside group
+// chats are dropped.
+func resolveMentions(message Message) []string {
+	if message.GroupId == "" || len(message.Mentions) == 0 {
+		return nil
+	}
+
+	g, err := group.FindGroup(message.GroupId)
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var mentions []string
+	add := func(userId string) {
+		if userId != message.SenderId && !seen[userId] {
+			seen[userId] = true
+			mentions = append(mentions, userId)
+		}
+	}
+
+	for _, mention := range message.Mentions {
+		if mention == mentionAll {
+			if g.IsAdmin(message.SenderId) {
+				for _, member := range g.Members {
+					add(member.UserID)
+				}
+			}
+			continue
+		}
+		if _, ok := g.GetMember(mention); ok {
+			add(mention)
+		}
+	}
+	return mentions
+}
+
+// notifyMentions bumps the unread-mention counter of every mentioned user and
+// sends a visible push to those who muted the group
+func notifyMentions(message Message) {
+	g, err := group.FindGroup(message.GroupId)
+	if err != nil {
+		fmt.Println("Error loading group for mentions:", err)
+		return
+	}
+
+	collection := db.GetCollection("chat_mentions")
+	for _, userId := range message.Mentions {
+		_, err := collection.UpdateOne(
+			context.TODO(),
+			bson.M{"user_id": userId, "chat_id": message.GroupId},
+			bson.M{
+				"$inc": bson.M{"unread": 1},
+				"$set": bson.M{"last_message_id": message.Id, "updated_at": message.ServerTS},
+			},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			fmt.Println("Failed to update mention counter:", err)
+		}
+
+		if member, ok := g.GetMember(userId); ok && member.Muted {
+			go fcm.SendFCMMentionNotification(userId, g.Title, "You were mentioned", map[string]string{
+				"signal":     "mention",
+				"group_id":   message.GroupId,
+				"message_id": message.Id,
+			})
+		}
+	}
+}
+
+// clearMentions resets userId's unread-mention counter for a group chat
+func clearMentions(userId, groupId string) {
+	_, err := db.GetCollection("chat_mentions").DeleteOne(context.TODO(), bson.M{"user_id": userId, "chat_id": groupId})
+	if err != nil {
+		fmt.Println("Failed to clear mention counter:", err)
+	}
+}
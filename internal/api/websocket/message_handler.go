@@ -0,0 +1,114 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gochat_server/internal/api/group"
+	"gochat_server/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetMessageHistory returns the current version of a message along with
+// every earlier revision, oldest first
+func GetMessageHistory(c *gin.Context) {
+	messageID := c.Param("id")
+	userID := c.Query("user_id")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var stored struct {
+		Message   `bson:",inline"`
+		Revisions []MessageRevision `bson:"revisions"`
+	}
+	err := db.GetCollection("messages").FindOne(ctx, bson.M{"_id": messageID}).Decode(&stored)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+
+	if !canViewMessage(userID, stored.Message) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not a participant of this chat"})
+		return
+	}
+
+	if stored.Revisions == nil {
+		stored.Revisions = []MessageRevision{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   stored.Message,
+		"revisions": stored.Revisions,
+	})
+}
+
+// GetChatMentions lists the messages in a group chat that mention the caller,
+// newest first. Pass the server_ts and _id of the last message seen as
+// ?before= and ?before_id= to page back through older mentions; messages
+// sent in the same second are told apart by their _id.
+func GetChatMentions(c *gin.Context) {
+	groupID := c.Param("id")
+	userID := c.Query("user_id")
+
+	g, err := group.FindGroup(groupID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+	if _, ok := g.GetMember(userID); !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this group"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	filter := bson.M{
+		"group_id":             groupID,
+		"mentions":             userID,
+		"deleted_for_everyone": bson.M{"$ne": 1},
+	}
+	if before := c.Query("before"); before != "" {
+		filter["$or"] = bson.A{
+			bson.M{"server_ts": bson.M{"$lt": before}},
+			bson.M{"server_ts": before, "_id": bson.M{"$lt": c.Query("before_id")}},
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "server_ts", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+	cursor, err := db.GetCollection("messages").Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch mentions"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	messages := []Message{}
+	if err := cursor.All(ctx, &messages); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding mentions"})
+		return
+	}
+
+	var counter struct {
+		Unread int `bson:"unread"`
+	}
+	db.GetCollection("chat_mentions").FindOne(ctx, bson.M{"user_id": userID, "chat_id": groupID}).Decode(&counter)
+
+	c.JSON(http.StatusOK, gin.H{
+		"mentions":        messages,
+		"unread_mentions": counter.Unread,
+	})
+}
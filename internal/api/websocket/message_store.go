@@ -0,0 +1,131 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gochat_server/internal/api/group"
+	"gochat_server/internal/api/media"
+	"gochat_server/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// storeMessage persists a routed message so the server can later act on it
+// (edits, deletes, history), and reports whether it was new. Re-sent messages
+// keep their first copy.
+func storeMessage(message Message) bool {
+	_, err := db.GetCollection("messages").InsertOne(context.TODO(), message)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		fmt.Println("Failed to persist message:", err)
+	}
+	return err == nil
+}
+
+func findMessage(messageId string) (Message, error) {
+	var message Message
+	err := db.GetCollection("messages").FindOne(context.TODO(), bson.M{"_id": messageId}).Decode(&message)
+	return message, err
+}
+
+// findMessagesByID loads the persisted messages with the given IDs, keyed by ID
+func findMessagesByID(ctx context.Context, ids []string) (map[string]Message, error) {
+	messages := make(map[string]Message)
+	if len(ids) == 0 {
+		return messages, nil
+	}
+
+	cursor, err := db.GetCollection("messages").Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var message Message
+		if err := cursor.Decode(&message); err != nil {
+			return nil, err
+		}
+		messages[message.Id] = message
+	}
+	return messages, cursor.Err()
+}
+
+// canViewMessage reports whether userId took part in the chat the message belongs to
+func canViewMessage(userId string, message Message) bool {
+	if message.SenderId == userId || message.ReceiverId == userId {
+		return true
+	}
+	if message.GroupId == "" {
+		return false
+	}
+	g, err := group.FindGroup(message.GroupId)
+	if err != nil {
+		return false
+	}
+	_, ok := g.GetMember(userId)
+	return ok
+}
+
+// tombstoneMessage replaces a persisted message with an empty placeholder that
+// keeps its place in the chat, and returns the tombstone
+func tombstoneMessage(stored Message, deletedBy string) (Message, error) {
+	tombstone := Message{
+		Id:                 stored.Id,
+		SenderId:           stored.SenderId,
+		ReceiverId:         stored.ReceiverId,
+		Timestamp:          stored.Timestamp,
+		ServerTS:           stored.ServerTS,
+		ChatId:             stored.ChatId,
+		GroupId:            stored.GroupId,
+		Type:               stored.Type,
+		Status:             stored.Status,
+		DeletedForEveryone: 1,
+		DeletedAt:          time.Now().Format(time.RFC3339),
+		DeletedBy:          deletedBy,
+	}
+	_, err := db.GetCollection("messages").ReplaceOne(context.TODO(), bson.M{"_id": stored.Id}, tombstone)
+	return tombstone, err
+}
+
+// purgeQueuedCopies drops undelivered copies of a message (and of its edits)
+// from every recipient's offline queue
+func purgeQueuedCopies(messageId string) {
+	_, err := db.GetCollection("offline_messages").DeleteMany(context.TODO(), bson.M{
+		"message.type":     bson.M{"$in": bson.A{"message", "edit_message"}},
+		"message.data._id": messageId,
+	})
+	if err != nil {
+		fmt.Println("Failed to purge queued copies of message:", err)
+	}
+}
+
+// deleteMessageMedia removes the media attached to a deleted message when its
+// author uploaded it and nothing else still carries it. Media forwarded from
+// someone else, made public, or sent in other messages is left for the media
+// GC to collect once it is unreferenced.
+func deleteMessageMedia(stored Message) {
+	m, err := media.Find(stored.MediaId)
+	if err != nil || m.OwnerID != stored.SenderId || m.Public {
+		return
+	}
+
+	filter := bson.M{
+		"_id":                  bson.M{"$ne": stored.Id},
+		"media_id":             stored.MediaId,
+		"deleted_for_everyone": bson.M{"$ne": 1},
+	}
+	if count, err := db.GetCollection("messages").CountDocuments(context.TODO(), filter); err != nil || count > 0 {
+		return
+	}
+	pending := bson.M{"message.media_id": stored.MediaId, "status": scheduledPending}
+	if count, err := db.GetCollection("scheduled_messages").CountDocuments(context.TODO(), pending); err != nil || count > 0 {
+		return
+	}
+
+	if err := media.Delete(stored.MediaId); err != nil {
+		fmt.Println("Failed to delete message media:", err)
+	}
+}
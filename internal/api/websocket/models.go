@@ -1,18 +1,32 @@
 package websocket
 
 type Message struct {
-	Id                 string `json:"_id"`
-	SenderId           string `json:"sender_id"`
-	ReceiverId         string `json:"receiver_id"`
-	Content            string `json:"content"`
-	Timestamp          string `json:"timestamp"`
-	ServerTS		   string `json:"server_ts"`
-	ChatId             string `json:"chat_id"`
-	GroupId            string `json:"group_id"`
-	Type               string `json:"type"`
-	Status             string `json:"status"`
-	DeletedForEveryone int    `json:"deleted_for_everyone"`
-	Edited             int    `json:"edited"`
+	Id                 string   `bson:"_id" json:"_id"`
+	SenderId           string   `bson:"sender_id" json:"sender_id"`
+	ReceiverId         string   `bson:"receiver_id" json:"receiver_id"`
+	Content            string   `bson:"content" json:"content"`
+	Timestamp          string   `bson:"timestamp" json:"timestamp"`
+	ServerTS           string   `bson:"server_ts" json:"server_ts"`
+	ChatId             string   `bson:"chat_id" json:"chat_id"`
+	GroupId            string   `bson:"group_id" json:"group_id"`
+	Type               string   `bson:"type" json:"type"`
+	Status             string   `bson:"status" json:"status"`
+	DeletedForEveryone int      `bson:"deleted_for_everyone" json:"deleted_for_everyone"`
+	Edited             int      `bson:"edited" json:"edited"`
+	EditedAt           string   `bson:"edited_at,omitempty" json:"edited_at,omitempty"`
+	MediaId            string   `bson:"media_id,omitempty" json:"media_id,omitempty"`
+	Mentions           []string `bson:"mentions,omitempty" json:"mentions,omitempty"` // user IDs; "@all" is expanded by the server
+	DeletedAt          string   `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	DeletedBy          string   `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+	BroadcastId        string   `bson:"broadcast_id,omitempty" json:"broadcast_id,omitempty"` // set on the 1:1 copies of a broadcast
+}
+
+// MessageRevision is a previous version of an edited message, kept on the
+// persisted message under "revisions"
+type MessageRevision struct {
+	Content    string `bson:"content" json:"content"`
+	CreatedAt  string `bson:"created_at" json:"created_at"`   // when this version was sent or edited in
+	ReplacedAt string `bson:"replaced_at" json:"replaced_at"` // when the next edit superseded it
 }
 
 type ReadAcknowledgment struct {
@@ -50,6 +64,7 @@ type DeletedForEveryoneMessage struct {
 	GroupId    string `json:"group_id"`
 	Timestamp  string `json:"timestamp"`
 	ServerTS   string `json:"server_ts"`
+	DeletedBy  string `json:"deleted_by,omitempty"`
 }
 
 type ErrorAcknowledgment struct {
@@ -59,6 +74,85 @@ type ErrorAcknowledgment struct {
 	ChatId     string `json:"chat_id"`
 	GroupId    string `json:"group_id"`
 	Timestamp  string `json:"timestamp"`
+	Error      string `json:"error"`
+}
+
+// ScheduledMessage is a message held by the server until SendAt, when the
+// scheduler hands it to the normal message routing path.
+type ScheduledMessage struct {
+	Id         string  `bson:"_id" json:"_id"`
+	SenderId   string  `bson:"sender_id" json:"sender_id"`
+	Message    Message `bson:"message" json:"message"`
+	SendAt     string  `bson:"send_at" json:"send_at"` // RFC3339, always UTC
+	Status     string  `bson:"status" json:"status"`   // pending, sent, cancelled or failed
+	LeaseOwner string  `bson:"lease_owner,omitempty" json:"-"`
+	LeaseUntil string  `bson:"lease_until,omitempty" json:"-"`
+	CreatedAt  string  `bson:"created_at" json:"created_at"`
+	UpdatedAt  string  `bson:"updated_at" json:"updated_at"`
+	SentAt     string  `bson:"sent_at,omitempty" json:"sent_at,omitempty"`
+	Error      string  `bson:"error,omitempty" json:"error,omitempty"` // why a failed message couldn't be sent
+}
+
+type ScheduleMessageRequest struct {
+	SendAt  string  `json:"send_at"`
+	Message Message `json:"message"`
+}
+
+type ScheduledAcknowledgment struct {
+	ScheduledId string `json:"scheduled_id"`
+	MessageId   string `json:"message_id"`
+	ChatId      string `json:"chat_id"`
+	GroupId     string `json:"group_id"`
+	SendAt      string `json:"send_at"`
+}
+
+// PinnedMessage is a message pinned to the top of a chat. ChatId is the
+// group ID for group chats. An empty ExpiresAt means the pin never expires.
+type PinnedMessage struct {
+	Id        string `bson:"_id" json:"_id"`
+	ChatId    string `bson:"chat_id" json:"chat_id"`
+	GroupId   string `bson:"group_id" json:"group_id"`
+	MessageId string `bson:"message_id" json:"message_id"`
+	PinnedBy  string `bson:"pinned_by" json:"pinned_by"`
+	PinnedAt  string `bson:"pinned_at" json:"pinned_at"`
+	ExpiresAt string `bson:"expires_at,omitempty" json:"expires_at,omitempty"` // RFC3339, always UTC
+}
+
+type PinMessageRequest struct {
+	MessageId string `json:"message_id"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// StarredMessage is one user's private bookmark on a message
+type StarredMessage struct {
+	UserId    string `bson:"user_id" json:"user_id"`
+	MessageId string `bson:"message_id" json:"message_id"`
+	ChatId    string `bson:"chat_id" json:"chat_id"`
+	GroupId   string `bson:"group_id" json:"group_id"`
+	StarredAt string `bson:"starred_at" json:"starred_at"`
+}
+
+// BroadcastMessageRequest sends Message to every recipient of a broadcast list
+type BroadcastMessageRequest struct {
+	ListId  string  `json:"list_id"`
+	Message Message `json:"message"`
+}
+
+type BroadcastAcknowledgment struct {
+	BroadcastId string `json:"broadcast_id"`
+	ListId      string `json:"list_id"`
+	Recipients  int    `json:"recipients"`
+	ServerTS    string `json:"server_ts"`
+}
+
+// ChannelPostRequest publishes a post to a channel. ClientId is echoed back in
+// the ack so the client can match it to the post the server created.
+type ChannelPostRequest struct {
+	ClientId  string `json:"client_id"`
+	ChannelId string `json:"channel_id"`
+	Content   string `json:"content"`
+	Type      string `json:"type"`
+	MediaId   string `json:"media_id"`
 }
 
 type IncomingMessage struct {
@@ -79,4 +173,4 @@ type FCMMessageContent struct {
 type Notification struct {
 	Title string `json:"title"`
 	Body  string `json:"body"`
-}
\ No newline at end of file
+}
@@ -0,0 +1,210 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gochat_server/config"
+	"gochat_server/internal/api/group"
+	"gochat_server/internal/db"
+	"gochat_server/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// handlePinMessage pins a message in its chat and tells every participant
+func handlePinMessage(userId string, incmsg IncomingMessage) {
+	var request PinMessageRequest
+	if err := utils.BindData(incmsg.Data, &request); err != nil {
+		fmt.Println("Error binding pin request:", err)
+		return
+	}
+
+	message, err := findMessage(request.MessageId)
+	if err != nil {
+		sendErrorAck(userId, Message{Id: request.MessageId}, "message not found")
+		return
+	}
+	if reason := checkCanPin(userId, message); reason != "" {
+		sendErrorAck(userId, message, reason)
+		return
+	}
+
+	expiresAt := ""
+	if request.ExpiresAt != "" {
+		t, err := time.Parse(time.RFC3339, request.ExpiresAt)
+		if err != nil || !t.After(time.Now()) {
+			sendErrorAck(userId, message, "expires_at must be a future RFC3339 timestamp")
+			return
+		}
+		expiresAt = t.UTC().Format(time.RFC3339)
+	}
+
+	chatKey := pinChatKey(message)
+	now := time.Now().UTC().Format(time.RFC3339)
+	collection := db.GetCollection("pinned_messages")
+
+	active, err := collection.CountDocuments(context.TODO(), bson.M{
+		"chat_id": chatKey,
+		"_id":     bson.M{"$ne": message.Id},
+		"$or":     pinActive(now),
+	})
+	if err != nil {
+		sendErrorAck(userId, message, "failed to pin message")
+		return
+	}
+	if active >= int64(config.Cfg.MaxPinnedMessages) {
+		sendErrorAck(userId, message, fmt.Sprintf("a chat can have at most %d pinned messages", config.Cfg.MaxPinnedMessages))
+		return
+	}
+
+	pin := PinnedMessage{
+		Id:        message.Id,
+		ChatId:    chatKey,
+		GroupId:   message.GroupId,
+		MessageId: message.Id,
+		PinnedBy:  userId,
+		PinnedAt:  now,
+		ExpiresAt: expiresAt,
+	}
+	_, err = collection.ReplaceOne(context.TODO(), bson.M{"_id": pin.Id}, pin, options.Replace().SetUpsert(true))
+	if err != nil {
+		fmt.Println("Failed to store pin:", err)
+		sendErrorAck(userId, message, "failed to pin message")
+		return
+	}
+
+	sendToRecipients("", message, map[string]interface{}{
+		"type": "pin_message",
+		"data": pin,
+	})
+}
+
+// handleUnpinMessage removes a pin and tells every participant
+func handleUnpinMessage(userId string, incmsg IncomingMessage) {
+	var request PinMessageRequest
+	if err := utils.BindData(incmsg.Data, &request); err != nil {
+		fmt.Println("Error binding unpin request:", err)
+		return
+	}
+
+	message, err := findMessage(request.MessageId)
+	if err != nil {
+		sendErrorAck(userId, Message{Id: request.MessageId}, "message not found")
+		return
+	}
+	if reason := checkCanPin(userId, message); reason != "" {
+		sendErrorAck(userId, message, reason)
+		return
+	}
+
+	_, err = db.GetCollection("pinned_messages").DeleteOne(context.TODO(), bson.M{"_id": message.Id})
+	if err != nil {
+		fmt.Println("Failed to remove pin:", err)
+		sendErrorAck(userId, message, "failed to unpin message")
+		return
+	}
+
+	sendToRecipients("", message, map[string]interface{}{
+		"type": "unpin_message",
+		"data": PinnedMessage{
+			Id:        message.Id,
+			ChatId:    pinChatKey(message),
+			GroupId:   message.GroupId,
+			MessageId: message.Id,
+			PinnedBy:  userId,
+		},
+	})
+}
+
+// GetPinnedMessages lists the unexpired pins of a chat (or group) together
+// with the pinned messages
+func GetPinnedMessages(c *gin.Context) {
+	chatID := c.Param("id")
+	userID := c.Query("user_id")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	opts := options.Find().SetSort(bson.D{{Key: "pinned_at", Value: -1}})
+	cursor, err := db.GetCollection("pinned_messages").Find(ctx, bson.M{"chat_id": chatID, "$or": pinActive(now)}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pinned messages"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var pins []PinnedMessage
+	if err := cursor.All(ctx, &pins); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding pinned messages"})
+		return
+	}
+
+	messages, err := findMessagesByID(ctx, pinMessageIDs(pins))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pinned messages"})
+		return
+	}
+
+	result := []gin.H{}
+	for _, pin := range pins {
+		message, ok := messages[pin.MessageId]
+		if !ok || !canViewMessage(userID, message) {
+			continue
+		}
+		result = append(result, gin.H{"pin": pin, "message": message})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pinned_messages": result})
+}
+
+// checkCanPin returns why userId may not pin or unpin message, or "". Any
+// participant may pin in a 1:1 chat; in groups it takes an admin unless the
+// group lets members pin.
+func checkCanPin(userId string, message Message) string {
+	if !canViewMessage(userId, message) {
+		return "not a participant of this chat"
+	}
+	if message.DeletedForEveryone == 1 {
+		return "message has been deleted"
+	}
+	if message.GroupId == "" {
+		return ""
+	}
+	g, err := group.FindGroup(message.GroupId)
+	if err != nil {
+		return "group not found"
+	}
+	if !g.IsAdmin(userId) && !g.MemberCanPin {
+		return "only admins can pin messages in this group"
+	}
+	return ""
+}
+
+func pinChatKey(message Message) string {
+	if message.GroupId != "" {
+		return message.GroupId
+	}
+	return message.ChatId
+}
+
+// pinActive matches pins that have not expired yet
+func pinActive(now string) bson.A {
+	return bson.A{
+		bson.M{"expires_at": bson.M{"$exists": false}},
+		bson.M{"expires_at": bson.M{"$gt": now}},
+	}
+}
+
+func pinMessageIDs(pins []PinnedMessage) []string {
+	ids := make([]string, 0, len(pins))
+	for _, pin := range pins {
+		ids = append(ids, pin.MessageId)
+	}
+	return ids
+}
@@ -0,0 +1,32 @@
+package websocket
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPinChatKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		message Message
+		want    string
+	}{
+		{"direct chat", Message{ChatId: "a_b"}, "a_b"},
+		{"group", Message{ChatId: "a_b", GroupId: "g1"}, "g1"},
+	}
+	for _, tt := range tests {
+		if got := pinChatKey(tt.message); got != tt.want {
+			t.Errorf("%s: pinChatKey() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPinMessageIDs(t *testing.T) {
+	pins := []PinnedMessage{{Id: "p1", MessageId: "m1"}, {Id: "p2", MessageId: "m2"}}
+	if got := pinMessageIDs(pins); !reflect.DeepEqual(got, []string{"m1", "m2"}) {
+		t.Errorf("pinMessageIDs() = %v, want [m1 m2]", got)
+	}
+	if got := pinMessageIDs(nil); got == nil || len(got) != 0 {
+		t.Errorf("pinMessageIDs(nil) = %#v, want an empty slice", got)
+	}
+}
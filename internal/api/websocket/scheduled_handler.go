@@ -0,0 +1,200 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gochat_server/internal/db"
+	"gochat_server/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type UpdateScheduledMessageRequest struct {
+	Content string `json:"content"`
+	SendAt  string `json:"send_at"`
+}
+
+// handleScheduleMessage stores a message to be sent later on the sender's behalf
+func handleScheduleMessage(userId string, incmsg IncomingMessage) {
+	var request ScheduleMessageRequest
+	if err := utils.BindData(incmsg.Data, &request); err != nil {
+		fmt.Println("Error binding scheduled message:", err)
+		return
+	}
+
+	message := request.Message
+	message.SenderId = userId
+	if message.Id == "" {
+		sendErrorAck(userId, message, "message _id is required")
+		return
+	}
+
+	sendAt, err := parseSendAt(request.SendAt)
+	if err != nil {
+		sendErrorAck(userId, message, err.Error())
+		return
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	scheduled := ScheduledMessage{
+		Id:        primitive.NewObjectID().Hex(),
+		SenderId:  userId,
+		Message:   message,
+		SendAt:    sendAt,
+		Status:    scheduledPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	_, err = db.GetCollection("scheduled_messages").InsertOne(context.TODO(), scheduled)
+	if err != nil {
+		fmt.Println("Failed to store scheduled message:", err)
+		sendErrorAck(userId, message, "failed to schedule message")
+		return
+	}
+
+	sendJsonMessage(userId, map[string]interface{}{
+		"type": "ack_scheduled",
+		"data": ScheduledAcknowledgment{
+			ScheduledId: scheduled.Id,
+			MessageId:   message.Id,
+			ChatId:      message.ChatId,
+			GroupId:     message.GroupId,
+			SendAt:      sendAt,
+		},
+	})
+}
+
+// GetScheduledMessages lists a user's scheduled messages, pending ones by default
+func GetScheduledMessages(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing user_id"})
+		return
+	}
+	status := c.DefaultQuery("status", scheduledPending)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "send_at", Value: 1}})
+	cursor, err := db.GetCollection("scheduled_messages").Find(ctx, bson.M{"sender_id": userID, "status": status}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch scheduled messages"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	scheduled := []ScheduledMessage{}
+	if err := cursor.All(ctx, &scheduled); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding scheduled messages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"scheduled_messages": scheduled})
+}
+
+// UpdateScheduledMessage changes the content or send time of a pending message
+func UpdateScheduledMessage(c *gin.Context) {
+	scheduledID := c.Param("id")
+	userID := c.Query("user_id")
+
+	var request UpdateScheduledMessageRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	set := bson.M{"updated_at": now}
+	if request.Content != "" {
+		set["message.content"] = request.Content
+	}
+	if request.SendAt != "" {
+		sendAt, err := parseSendAt(request.SendAt)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		set["send_at"] = sendAt
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := db.GetCollection("scheduled_messages")
+	result, err := collection.UpdateOne(ctx, editableScheduledFilter(scheduledID, userID, now), bson.M{"$set": set})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update scheduled message"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Scheduled message not found or already being sent"})
+		return
+	}
+
+	var scheduled ScheduledMessage
+	if err := collection.FindOne(ctx, bson.M{"_id": scheduledID}).Decode(&scheduled); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch scheduled message"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Scheduled message updated", "scheduled_message": scheduled})
+}
+
+// CancelScheduledMessage cancels a pending message so it is never sent
+func CancelScheduledMessage(c *gin.Context) {
+	scheduledID := c.Param("id")
+	userID := c.Query("user_id")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	result, err := db.GetCollection("scheduled_messages").UpdateOne(
+		ctx,
+		editableScheduledFilter(scheduledID, userID, now),
+		bson.M{"$set": bson.M{"status": scheduledCancelled, "updated_at": now}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel scheduled message"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Scheduled message not found or already being sent"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Scheduled message cancelled"})
+}
+
+// editableScheduledFilter matches a pending message owned by userID that no
+// scheduler instance is currently dispatching
+func editableScheduledFilter(scheduledID, userID, now string) bson.M {
+	return bson.M{
+		"_id":       scheduledID,
+		"sender_id": userID,
+		"status":    scheduledPending,
+		"$or":       leaseAvailable(now),
+	}
+}
+
+// parseSendAt validates a client supplied send time and normalises it to UTC
+// so that send_at values compare correctly as strings
+func parseSendAt(value string) (string, error) {
+	sendAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return "", errors.New("send_at must be an RFC3339 timestamp")
+	}
+	if !sendAt.After(time.Now()) {
+		return "", errors.New("send_at must be in the future")
+	}
+	return sendAt.UTC().Format(time.RFC3339), nil
+}
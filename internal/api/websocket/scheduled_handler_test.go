@@ -0,0 +1,35 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSendAt(t *testing.T) {
+	future := time.Now().Add(time.Hour).Truncate(time.Second)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"future UTC", future.UTC().Format(time.RFC3339), future.UTC().Format(time.RFC3339), false},
+		{"future with offset", future.In(time.FixedZone("", 5*3600)).Format(time.RFC3339), future.UTC().Format(time.RFC3339), false},
+		{"past", past.Format(time.RFC3339), "", true},
+		{"not a timestamp", "tomorrow", "", true},
+		{"empty", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSendAt(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSendAt(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseSendAt(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"gochat_server/config"
+	"gochat_server/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	scheduledPending   = "pending"
+	scheduledSent      = "sent"
+	scheduledCancelled = "cancelled"
+	scheduledFailed    = "failed"
+)
+
+// instanceId identifies this server process when it claims scheduled messages,
+// so that several instances can share the scheduled_messages collection.
+var instanceId = func() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), primitive.NewObjectID().Hex())
+}()
+
+// RunScheduler dispatches due scheduled messages until the process exits.
+func RunScheduler() {
+	ticker := time.NewTicker(config.Cfg.SchedulerInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		dispatchDueMessages()
+	}
+}
+
+// dispatchDueMessages claims due messages one at a time and routes them
+// through sendMessage. A claim is a lease: if this instance dies before
+// marking the message sent, another instance picks it up once the lease ends.
+func dispatchDueMessages() {
+	for {
+		scheduled, err := claimDueMessage()
+		if err == mongo.ErrNoDocuments {
+			return
+		}
+		if err != nil {
+			fmt.Println("Error claiming scheduled message:", err)
+			return
+		}
+
+		now := time.Now().UTC().Format(time.RFC3339)
+		scheduled.Message.Timestamp = now
+
+		// A message the sender may no longer send (e.g. they lost the right
+		// to post in the group) is marked failed rather than sent
+		set := bson.M{"status": scheduledSent, "sent_at": now, "updated_at": now}
+		if err := sendMessage(scheduled.SenderId, scheduled.Message); err != nil {
+			set = bson.M{"status": scheduledFailed, "error": err.Error(), "updated_at": now}
+		}
+
+		// Settle it unless another instance has claimed it since. A send that
+		// outlasted the lease still settles here, so it isn't sent again
+		// once nobody else has picked it up.
+		_, err = db.GetCollection("scheduled_messages").UpdateOne(
+			context.TODO(),
+			bson.M{"_id": scheduled.Id, "lease_owner": instanceId},
+			bson.M{
+				"$set":   set,
+				"$unset": bson.M{"lease_owner": "", "lease_until": ""},
+			},
+		)
+		if err != nil {
+			fmt.Println("Failed to settle scheduled message:", err)
+		}
+	}
+}
+
+func claimDueMessage() (ScheduledMessage, error) {
+	now := time.Now().UTC()
+	nowStr := now.Format(time.RFC3339)
+
+	filter := bson.M{
+		"status":  scheduledPending,
+		"send_at": bson.M{"$lte": nowStr},
+		"$or":     leaseAvailable(nowStr),
+	}
+	update := bson.M{"$set": bson.M{
+		"lease_owner": instanceId,
+		"lease_until": now.Add(config.Cfg.SchedulerLease).Format(time.RFC3339),
+	}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "send_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var scheduled ScheduledMessage
+	err := db.GetCollection("scheduled_messages").
+		FindOneAndUpdate(context.TODO(), filter, update, opts).
+		Decode(&scheduled)
+	return scheduled, err
+}
+
+// leaseAvailable matches scheduled messages no instance currently holds.
+func leaseAvailable(now string) bson.A {
+	return bson.A{
+		bson.M{"lease_until": bson.M{"$exists": false}},
+		bson.M{"lease_until": bson.M{"$lt": now}},
+	}
+}
@@ -0,0 +1,129 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"gochat_server/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type StarMessageRequest struct {
+	MessageId string `json:"message_id" binding:"required"`
+}
+
+// StarMessage privately bookmarks a message for the calling user
+func StarMessage(c *gin.Context) {
+	userID := c.Query("user_id")
+
+	var request StarMessageRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	message, err := findMessage(request.MessageId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+	if !canViewMessage(userID, message) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not a participant of this chat"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	star := StarredMessage{
+		UserId:    userID,
+		MessageId: message.Id,
+		ChatId:    message.ChatId,
+		GroupId:   message.GroupId,
+		StarredAt: time.Now().Format(time.RFC3339),
+	}
+	_, err = db.GetCollection("starred_messages").UpdateOne(
+		ctx,
+		bson.M{"user_id": userID, "message_id": message.Id},
+		bson.M{"$setOnInsert": star},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to star message"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Message starred", "starred": star})
+}
+
+// UnstarMessage removes the calling user's bookmark on a message
+func UnstarMessage(c *gin.Context) {
+	userID := c.Query("user_id")
+	messageID := c.Param("message_id")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := db.GetCollection("starred_messages").DeleteOne(ctx, bson.M{"user_id": userID, "message_id": messageID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unstar message"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Message unstarred"})
+}
+
+// GetStarredMessages lists the calling user's starred messages across all
+// chats, newest first, optionally narrowed to one chat with ?chat_id=
+func GetStarredMessages(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing user_id"})
+		return
+	}
+
+	filter := bson.M{"user_id": userID}
+	if chatID := c.Query("chat_id"); chatID != "" {
+		filter["$or"] = bson.A{bson.M{"chat_id": chatID}, bson.M{"group_id": chatID}}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "starred_at", Value: -1}})
+	cursor, err := db.GetCollection("starred_messages").Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch starred messages"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var stars []StarredMessage
+	if err := cursor.All(ctx, &stars); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding starred messages"})
+		return
+	}
+
+	ids := make([]string, 0, len(stars))
+	for _, star := range stars {
+		ids = append(ids, star.MessageId)
+	}
+	messages, err := findMessagesByID(ctx, ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch starred messages"})
+		return
+	}
+
+	result := []gin.H{}
+	for _, star := range stars {
+		if message, ok := messages[star.MessageId]; ok {
+			result = append(result, gin.H{"starred": star, "message": message})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"starred_messages": result})
+}
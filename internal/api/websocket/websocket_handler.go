@@ -3,12 +3,17 @@ package websocket
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"sync"
 	"time"
 
+	"gochat_server/config"
+	"gochat_server/internal/api/broadcast"
 	"gochat_server/internal/api/fcm"
+	"gochat_server/internal/api/group"
+	"gochat_server/internal/api/media"
 	"gochat_server/internal/db"
 	"gochat_server/internal/utils"
 
@@ -25,19 +30,25 @@ var (
 	onlineUsers = make(map[string]*websocket.Conn)
 	onlineUsersMutex sync.RWMutex
 
-	messageHandlers = map[string]func(incmsg IncomingMessage){
-		"message":           handleMessageType,
-		"ack_read":          handleReadAck,
-		"ack_sent":          handleSentAck,
-		"ack_delivered":     handleDeliveredAck,
-		"edit_message":      handleEditMessage,
-		"delete_message":    handleDeleteMessage,
-		"webrtc_offer":      handleWebRTCOffer,
-		"webrtc_answer":     handleWebRTCAnswer,
-		"webrtc_candidate":  handleICECandidate,
-		"webrtc_delivered":  handleWebRTCDelivered,
-		"webrtc_hangup":     handleWebRTCHangup,
-		"webrtc_decline":    handleWebRTCDecline,
+	messageHandlers = map[string]func(userId string, incmsg IncomingMessage){
+		"message":              handleMessageType,
+		"ack_read":             handleReadAck,
+		"ack_sent":             handleSentAck,
+		"ack_delivered":        handleDeliveredAck,
+		"edit_message":         handleEditMessage,
+		"delete_message":       handleDeleteMessage,
+		"delete_message_admin": handleAdminDeleteMessage,
+		"schedule_message":     handleScheduleMessage,
+		"broadcast_message":    handleBroadcastMessage,
+		"channel_post":         handleChannelPost,
+		"pin_message":          handlePinMessage,
+		"unpin_message":        handleUnpinMessage,
+		"webrtc_offer":         handleWebRTCOffer,
+		"webrtc_answer":        handleWebRTCAnswer,
+		"webrtc_candidate":     handleICECandidate,
+		"webrtc_delivered":     handleWebRTCDelivered,
+		"webrtc_hangup":        handleWebRTCHangup,
+		"webrtc_decline":       handleWebRTCDecline,
 	}
 )
 
@@ -72,11 +83,11 @@ func WebSocketHandler(c *gin.Context) {
 		onlineUsersMutex.Lock()
 		delete(onlineUsers, userId)
 		onlineUsersMutex.Unlock()
+		connWriteLocks.Delete(conn)
 
 		fmt.Println("WebSocket connection closed for user:", userId)
 	}()
 
-
 	fmt.Println("WebSocket connection established for user:", userId)
 
 	// Handle WebSocket communication
@@ -97,23 +108,53 @@ func WebSocketHandler(c *gin.Context) {
 		}
 
 		if handler, ok := messageHandlers[incmsg.Type]; ok {
-			handler(incmsg)
+			handler(userId, incmsg)
 		} else {
 			fmt.Println("Unknown message type:", incmsg.Type)
 		}
 	}
 }
 
-func handleMessageType(incmsg IncomingMessage) {
+func handleMessageType(userId string, incmsg IncomingMessage) {
 	var message Message
 	if err := utils.BindData(incmsg.Data, &message); err != nil {
 		fmt.Println("Invalid Message Payload" + err.Error())
 		return
 	}
+	sendMessage(userId, message)
+}
+
+// sendMessage accepts and routes a message from userId. A rejected message is
+// answered with an error ack, and the reason is returned.
+func sendMessage(userId string, message Message) error {
+	// The connection, not the payload, says who is sending
+	message.SenderId = userId
+
+	if reason := checkCanSend(userId, message); reason != "" {
+		sendErrorAck(userId, message, reason)
+		return errors.New(reason)
+	}
+
+	message, isNew := acceptMessage(message)
+	sendSentAck(message)
+	forwardMessage(userId, message, isNew)
+	return nil
+}
 
+// acceptMessage stamps and persists a message the sender is allowed to send.
+// It also reports whether the message is new rather than a client resend.
+func acceptMessage(message Message) (Message, bool) {
 	message.ServerTS = time.Now().Format(time.RFC3339)
 	message.Status = "sent"
+	message.Mentions = resolveMentions(message)
+	isNew := storeMessage(message)
+	if message.MediaId != "" {
+		media.ShareWithChat(message.MediaId, message.GroupId, message.SenderId, message.ReceiverId)
+	}
+	return message, isNew
+}
 
+func sendSentAck(message Message) {
 	var sentAck SentAcknowledgment
 	sentAck.MessageId = message.Id
 	sentAck.SenderId = message.SenderId
@@ -130,46 +171,218 @@ func handleMessageType(incmsg IncomingMessage) {
 			"data": sentAck,
 		},
 	)
+}
 
-	sendJsonMessage(message.ReceiverId, incmsg)
+// forwardMessage delivers an accepted message to its receiver, or to every
+// member when it is addressed to a group, and notifies mentioned users the
+// first time the message is sent
+func forwardMessage(userId string, message Message, isNew bool) {
+	incmsg := IncomingMessage{Type: "message", Data: message}
+
+	if message.GroupId != "" && (message.ReceiverId == "" || message.ReceiverId == message.GroupId) {
+		// Addressed to the group itself (e.g. a community announcement), so
+		// the server fans it out to every member
+		sendToRecipients(userId, message, incmsg)
+	} else {
+		sendJsonMessage(message.ReceiverId, incmsg)
+	}
+
+	if isNew && len(message.Mentions) > 0 {
+		go notifyMentions(message)
+	}
 }
 
-func handleEditMessage(incmsg IncomingMessage) {
+// checkCanSend returns why userId may not send message, or "". Group
+// messages need a member, and an admin when the group is in announcement
+// mode (MemberCanSend off).
+func checkCanSend(userId string, message Message) string {
+	if message.MediaId != "" && !media.CanAccess(userId, message.MediaId) {
+		return "media not found"
+	}
+	if message.GroupId == "" {
+		return ""
+	}
+	g, err := group.FindGroup(message.GroupId)
+	if err != nil {
+		return "group not found"
+	}
+	return checkGroupSender(userId, g)
+}
+
+// checkGroupSender returns why userId may not send to g, or ""
+func checkGroupSender(userId string, g group.Group) string {
+	if _, ok := g.GetMember(userId); !ok {
+		return "not a member of this group"
+	}
+	if !g.MemberCanSend && !g.IsAdmin(userId) {
+		return "only admins can send messages to this group"
+	}
+	return ""
+}
+
+// handleEditMessage replaces the text of a persisted message, keeping the old
+// version as a revision. Only the author may edit, and only text messages
+// younger than the configured edit window.
+func handleEditMessage(userId string, incmsg IncomingMessage) {
 	var message Message
 	if err := utils.BindData(incmsg.Data, &message); err != nil {
 		fmt.Println("Error binding message:", err)
 		return
 	}
 
+	stored, err := findMessage(message.Id)
+	if err != nil {
+		sendErrorAck(userId, message, "message not found")
+		return
+	}
+
+	if reason := checkEditable(userId, stored, message.Content); reason != "" {
+		sendErrorAck(userId, message, reason)
+		return
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	revision := MessageRevision{
+		Content:    stored.Content,
+		CreatedAt:  stored.ServerTS,
+		ReplacedAt: now,
+	}
+	if stored.EditedAt != "" {
+		revision.CreatedAt = stored.EditedAt
+	}
+
+	// Matching on the old content makes concurrent edits fail instead of
+	// silently dropping a revision
+	result, err := db.GetCollection("messages").UpdateOne(
+		context.TODO(),
+		bson.M{"_id": stored.Id, "content": stored.Content},
+		bson.M{
+			"$set":  bson.M{"content": message.Content, "edited": 1, "edited_at": now},
+			"$push": bson.M{"revisions": revision},
+		},
+	)
+	if err != nil || result.MatchedCount == 0 {
+		fmt.Println("Failed to store message edit:", err)
+		sendErrorAck(userId, message, "failed to edit message")
+		return
+	}
+
+	stored.Content = message.Content
+	stored.Edited = 1
+	stored.EditedAt = now
+
 	var sentAck SentAcknowledgment
-	sentAck.MessageId = message.Id
-	sentAck.SenderId = message.SenderId
-	sentAck.ReceiverId = message.ReceiverId
-	sentAck.ServerTS = message.ServerTS
+	sentAck.MessageId = stored.Id
+	sentAck.SenderId = stored.SenderId
+	sentAck.ReceiverId = stored.ReceiverId
+	sentAck.ServerTS = now
 	sentAck.Timestamp = message.Timestamp
-	sentAck.ChatId = message.ChatId
-	sentAck.GroupId = message.GroupId
+	sentAck.ChatId = stored.ChatId
+	sentAck.GroupId = stored.GroupId
 
-	sendJsonMessage(message.SenderId, map[string]interface{}{
+	sendJsonMessage(userId, map[string]interface{}{
 		"type": "ack_sent",
 		"data": sentAck,
 	})
 
-	message.Edited = 1
-
-	sendJsonMessage(message.ReceiverId, map[string]interface{}{
+	sendToRecipients(userId, stored, map[string]interface{}{
 		"type": "edit_message",
-		"data": incmsg.Data,
+		"data": stored,
 	})
 }
 
-func handleDeleteMessage(incmsg IncomingMessage) {
+// checkEditable returns why userId may not change stored to content, or ""
+func checkEditable(userId string, stored Message, content string) string {
+	if stored.SenderId != userId {
+		return "only the author can edit a message"
+	}
+	if stored.DeletedForEveryone == 1 {
+		return "message has been deleted"
+	}
+	if stored.Type != "" && stored.Type != "text" {
+		return "only text messages can be edited"
+	}
+	if content == "" {
+		return "edited content cannot be empty"
+	}
+	sentAt, err := time.Parse(time.RFC3339, stored.ServerTS)
+	if err != nil || time.Since(sentAt) > config.Cfg.MessageEditWindow {
+		return "edit window has expired"
+	}
+	return ""
+}
+
+// checkDeletable returns why userId may not delete stored for everyone, or "".
+// Authors delete their own messages; as an admin, g is the message's group.
+func checkDeletable(userId string, stored Message, asAdmin bool, g group.Group) string {
+	if asAdmin {
+		if stored.GroupId == "" || g.ID != stored.GroupId || !g.IsAdmin(userId) {
+			return "only group admins can delete other members' messages"
+		}
+		return ""
+	}
+	if stored.SenderId != userId {
+		return "only the author can delete a message for everyone"
+	}
+	return ""
+}
+
+func handleDeleteMessage(userId string, incmsg IncomingMessage) {
+	deleteForEveryone(userId, incmsg, false)
+}
+
+// handleAdminDeleteMessage lets a group admin delete anyone's group message
+func handleAdminDeleteMessage(userId string, incmsg IncomingMessage) {
+	deleteForEveryone(userId, incmsg, true)
+}
+
+// deleteForEveryone tombstones the persisted message, drops still-queued
+// copies, removes attached media and tells every recipient about it
+func deleteForEveryone(userId string, incmsg IncomingMessage, asAdmin bool) {
 	var deleteMessage DeletedForEveryoneMessage
 	if err := utils.BindData(incmsg.Data, &deleteMessage); err != nil {
 		fmt.Println("Error binding message:", err)
 		return
 	}
 
+	stored, err := findMessage(deleteMessage.Id)
+	if err != nil {
+		sendErrorAck(userId, Message{Id: deleteMessage.Id, ChatId: deleteMessage.ChatId, GroupId: deleteMessage.GroupId}, "message not found")
+		return
+	}
+
+	var g group.Group
+	if asAdmin && stored.GroupId != "" {
+		g, _ = group.FindGroup(stored.GroupId)
+	}
+	if reason := checkDeletable(userId, stored, asAdmin, g); reason != "" {
+		sendErrorAck(userId, stored, reason)
+		return
+	}
+
+	tombstone, err := tombstoneMessage(stored, userId)
+	if err != nil {
+		fmt.Println("Failed to tombstone message:", err)
+		sendErrorAck(userId, stored, "failed to delete message")
+		return
+	}
+	purgeQueuedCopies(stored.Id)
+	db.GetCollection("pinned_messages").DeleteOne(context.TODO(), bson.M{"_id": stored.Id})
+	if stored.MediaId != "" {
+		deleteMessageMedia(stored)
+	}
+
+	deleteMessage = DeletedForEveryoneMessage{
+		Id:         tombstone.Id,
+		SenderId:   tombstone.SenderId,
+		ReceiverId: tombstone.ReceiverId,
+		ChatId:     tombstone.ChatId,
+		GroupId:    tombstone.GroupId,
+		Timestamp:  tombstone.Timestamp,
+		ServerTS:   tombstone.DeletedAt,
+		DeletedBy:  userId,
+	}
+
 	var sentAck SentAcknowledgment
 	sentAck.MessageId = deleteMessage.Id
 	sentAck.SenderId = deleteMessage.SenderId
@@ -179,18 +392,18 @@ func handleDeleteMessage(incmsg IncomingMessage) {
 	sentAck.ChatId = deleteMessage.ChatId
 	sentAck.GroupId = deleteMessage.GroupId
 
-	sendJsonMessage(deleteMessage.SenderId, map[string]interface{}{
+	sendJsonMessage(userId, map[string]interface{}{
 		"type": "ack_sent",
 		"data": sentAck,
 	})
 
-	sendJsonMessage(deleteMessage.ReceiverId, map[string]interface{}{
+	sendToRecipients(userId, stored, map[string]interface{}{
 		"type": "delete_message",
-		"data": incmsg.Data,
+		"data": deleteMessage,
 	})
 }
 
-func handleReadAck(incmsg IncomingMessage) {
+func handleReadAck(userId string, incmsg IncomingMessage) {
 	var ackData ReadAcknowledgment
 	if err := utils.BindData(incmsg.Data, &ackData); err != nil {
 		fmt.Println("Error binding read acknowledgment:", err)
@@ -198,9 +411,15 @@ func handleReadAck(incmsg IncomingMessage) {
 	}
 	// fmt.Printf("Read acknowledgment received: %+v\n", ackData)
 	sendJsonMessage(ackData.SenderId, incmsg)
+
+	if ackData.GroupId != "" {
+		clearMentions(userId, ackData.GroupId)
+	} else if err := broadcast.MarkRead(ackData.SenderId, userId); err != nil {
+		fmt.Println("Failed to update broadcast read status:", err)
+	}
 }
 
-func handleSentAck(incmsg IncomingMessage) {
+func handleSentAck(userId string, incmsg IncomingMessage) {
 	var ackData SentAcknowledgment
 	if err := utils.BindData(incmsg.Data, &ackData); err != nil {
 		fmt.Println("Error binding sent acknowledgment:", err)
@@ -210,7 +429,7 @@ func handleSentAck(incmsg IncomingMessage) {
 	sendJsonMessage(ackData.ReceiverId, incmsg)
 }
 
-func handleDeliveredAck(incmsg IncomingMessage) {
+func handleDeliveredAck(userId string, incmsg IncomingMessage) {
 	var ackData DeliveredAcknowledgment
 	if err := utils.BindData(incmsg.Data, &ackData); err != nil {
 		fmt.Println("Error binding delivered acknowledgment:", err)
@@ -228,34 +447,38 @@ func handleDeliveredAck(incmsg IncomingMessage) {
 	if err != nil {
 		fmt.Println("Failed to delete delivered message:", err)
 	}
-}
 
+	if ackData.GroupId == "" {
+		if err := broadcast.MarkDelivered(ackData.MessageId); err != nil {
+			fmt.Println("Failed to update broadcast delivery status:", err)
+		}
+	}
+}
 
-func handleWebRTCOffer(incmsg IncomingMessage) {
+func handleWebRTCOffer(userId string, incmsg IncomingMessage) {
 	sendJsonMessage(utils.GetReceiverId(incmsg.Data), incmsg)
 }
 
-func handleWebRTCAnswer(incmsg IncomingMessage) {
+func handleWebRTCAnswer(userId string, incmsg IncomingMessage) {
 	sendJsonMessage(utils.GetReceiverId(incmsg.Data), incmsg)
 }
 
-func handleWebRTCDelivered(incmsg IncomingMessage) {
+func handleWebRTCDelivered(userId string, incmsg IncomingMessage) {
 	sendJsonMessage(utils.GetReceiverId(incmsg.Data), incmsg)
 }
 
-func handleICECandidate(incmsg IncomingMessage) {
+func handleICECandidate(userId string, incmsg IncomingMessage) {
 	sendJsonMessage(utils.GetReceiverId(incmsg.Data), incmsg)
 }
 
-func handleWebRTCHangup(incmsg IncomingMessage) {
+func handleWebRTCHangup(userId string, incmsg IncomingMessage) {
 	sendJsonMessage(utils.GetReceiverId(incmsg.Data), incmsg)
 }
 
-func handleWebRTCDecline(incmsg IncomingMessage) {
+func handleWebRTCDecline(userId string, incmsg IncomingMessage) {
 	sendJsonMessage(utils.GetReceiverId(incmsg.Data), incmsg)
 }
 
-
 // markOnline marks the user as online by storing their WebSocket connection
 func markOnline(userId string, conn *websocket.Conn) bool {
 	if userId == "" {
@@ -275,11 +498,16 @@ func getOnlineUser(userId string) (*websocket.Conn, bool) {
 	return conn, exists
 }
 
+// SendJsonMessage delivers data to a user, queueing it while they are offline
+func SendJsonMessage(receiverId string, data interface{}) error {
+	return sendJsonMessage(receiverId, data)
+}
+
 func sendJsonMessage(receiverId string, data interface{}) error {
 	recConn, exists := getOnlineUser(receiverId)
 	if exists {
 		// Receiver is online — send immediately
-		return recConn.WriteJSON(data)
+		return writeJSON(recConn, data)
 	}
 
 	// Receiver is offline — send FCM wake signal only
@@ -288,6 +516,59 @@ func sendJsonMessage(receiverId string, data interface{}) error {
 	return nil
 }
 
+// connWriteLocks holds a mutex per connection: a connection supports only one
+// writer at a time, and messages reach a user from many goroutines
+var connWriteLocks sync.Map
+
+// writeJSON writes data to conn, waiting for any other write to it to finish
+func writeJSON(conn *websocket.Conn, data interface{}) error {
+	lock, _ := connWriteLocks.LoadOrStore(conn, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+	return conn.WriteJSON(data)
+}
+
+// sendToRecipients delivers data to everyone in message's chat except
+// userId: every group member for group messages, otherwise the receiver
+// (and the original sender, when someone else is acting on the message)
+func sendToRecipients(userId string, message Message, data interface{}) {
+	if message.GroupId == "" {
+		for _, recipient := range []string{message.SenderId, message.ReceiverId} {
+			if recipient != "" && recipient != userId {
+				sendJsonMessage(recipient, data)
+			}
+		}
+		return
+	}
+
+	g, err := group.FindGroup(message.GroupId)
+	if err != nil {
+		fmt.Println("Error loading group for fan-out:", err)
+		return
+	}
+	for _, member := range g.Members {
+		if member.UserID != userId {
+			sendJsonMessage(member.UserID, data)
+		}
+	}
+}
+
+// sendErrorAck tells userId that the server rejected message, and why
+func sendErrorAck(userId string, message Message, reason string) {
+	sendJsonMessage(userId, map[string]interface{}{
+		"type": "ack_error",
+		"data": ErrorAcknowledgment{
+			MessageId:  message.Id,
+			SenderId:   message.SenderId,
+			ReceiverId: message.ReceiverId,
+			ChatId:     message.ChatId,
+			GroupId:    message.GroupId,
+			Timestamp:  message.Timestamp,
+			Error:      reason,
+		},
+	})
+}
+
 func storeOfflineMessage(receiverId string, data interface{}) {
 	go fcm.SendFCMWakeSignal(receiverId)
 
@@ -326,8 +607,8 @@ func deliverOfflineMessages(userId string, conn *websocket.Conn) {
 			fmt.Println("Error decoding message:", err)
 			continue
 		}
-		
-		if err := conn.WriteJSON(msg["message"]); err != nil {
+
+		if err := writeJSON(conn, msg["message"]); err != nil {
 			fmt.Printf("Failed to deliver message to %s: %v\n", userId, err)
 		}
 	}
@@ -0,0 +1,112 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"gochat_server/config"
+	"gochat_server/internal/api/group"
+)
+
+func TestCheckGroupSender(t *testing.T) {
+	open := group.Group{
+		ID:            "g1",
+		MemberCanSend: true,
+		Members:       []group.GroupMember{{UserID: "admin", IsAdmin: true}, {UserID: "member"}},
+	}
+	announcement := open
+	announcement.MemberCanSend = false
+
+	tests := []struct {
+		name   string
+		userId string
+		group  group.Group
+		want   string
+	}{
+		{"member of open group", "member", open, ""},
+		{"admin of open group", "admin", open, ""},
+		{"outsider", "outsider", open, "not a member of this group"},
+		{"member of admin-only group", "member", announcement, "only admins can send messages to this group"},
+		{"admin of admin-only group", "admin", announcement, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkGroupSender(tt.userId, tt.group); got != tt.want {
+				t.Errorf("checkGroupSender(%q) = %q, want %q", tt.userId, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckEditable(t *testing.T) {
+	defer func(window time.Duration) { config.Cfg.MessageEditWindow = window }(config.Cfg.MessageEditWindow)
+	config.Cfg.MessageEditWindow = 15 * time.Minute
+
+	recent := time.Now().Add(-time.Minute).Format(time.RFC3339)
+	old := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	text := Message{SenderId: "alice", Type: "text", ServerTS: recent}
+
+	with := func(change func(*Message)) Message {
+		message := text
+		change(&message)
+		return message
+	}
+
+	tests := []struct {
+		name    string
+		userId  string
+		stored  Message
+		content string
+		want    string
+	}{
+		{"author within window", "alice", text, "fixed", ""},
+		{"untyped message", "alice", with(func(m *Message) { m.Type = "" }), "fixed", ""},
+		{"someone else", "bob", text, "fixed", "only the author can edit a message"},
+		{"deleted", "alice", with(func(m *Message) { m.DeletedForEveryone = 1 }), "fixed", "message has been deleted"},
+		{"image", "alice", with(func(m *Message) { m.Type = "image" }), "fixed", "only text messages can be edited"},
+		{"empty content", "alice", text, "", "edited content cannot be empty"},
+		{"window expired", "alice", with(func(m *Message) { m.ServerTS = old }), "fixed", "edit window has expired"},
+		{"unknown send time", "alice", with(func(m *Message) { m.ServerTS = "" }), "fixed", "edit window has expired"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkEditable(tt.userId, tt.stored, tt.content); got != tt.want {
+				t.Errorf("checkEditable() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckDeletable(t *testing.T) {
+	g := group.Group{
+		ID:      "g1",
+		Members: []group.GroupMember{{UserID: "admin", IsAdmin: true}, {UserID: "alice"}, {UserID: "bob"}},
+	}
+	groupMessage := Message{SenderId: "alice", GroupId: "g1"}
+	direct := Message{SenderId: "alice", ReceiverId: "bob"}
+
+	tests := []struct {
+		name    string
+		userId  string
+		stored  Message
+		asAdmin bool
+		group   group.Group
+		want    string
+	}{
+		{"author", "alice", direct, false, group.Group{}, ""},
+		{"receiver", "bob", direct, false, group.Group{}, "only the author can delete a message for everyone"},
+		{"other group member", "bob", groupMessage, false, g, "only the author can delete a message for everyone"},
+		{"admin without admin delete", "admin", groupMessage, false, g, "only the author can delete a message for everyone"},
+		{"group admin", "admin", groupMessage, true, g, ""},
+		{"member as admin", "bob", groupMessage, true, g, "only group admins can delete other members' messages"},
+		{"admin of another group", "admin", Message{SenderId: "alice", GroupId: "g2"}, true, g, "only group admins can delete other members' messages"},
+		{"admin delete of direct message", "admin", direct, true, group.Group{}, "only group admins can delete other members' messages"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkDeletable(tt.userId, tt.stored, tt.asAdmin, tt.group); got != tt.want {
+				t.Errorf("checkDeletable() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,12 @@ package main
 
 import (
 	"gochat_server/config"
+	"gochat_server/internal/api/broadcast"
+	"gochat_server/internal/api/channel"
+	"gochat_server/internal/api/community"
+	"gochat_server/internal/api/group"
+	"gochat_server/internal/api/media"
+	"gochat_server/internal/api/websocket"
 	"gochat_server/internal/db"
 	"gochat_server/pkg/server"
 	"log"
@@ -10,6 +16,34 @@ import (
 func main() {
 	config.LoadConfig()
 	db.ConnectDB()
+	websocket.EnsureIndexes()
+	group.EnsureIndexes()
+	group.MigrateDefaults()
+	broadcast.EnsureIndexes()
+	channel.EnsureIndexes()
+	media.EnsureIndexes()
+	media.PublishPictures()
+
+	// Let group handlers push events to members over WebSocket
+	group.SetNotifier(websocket.SendJsonMessage)
+	group.SetCommunityMemberHook(community.MemberJoinedGroup)
+	group.SetCommunityMemberLeftHook(community.MemberLeftGroup)
+	group.SetCommunityGroupDeletedHook(community.GroupDeleted)
+
+	// Let media jobs report progress to uploaders over WebSocket
+	media.SetNotifier(websocket.SendJsonMessage)
+
+	// Dispatch scheduled messages in the background
+	go websocket.RunScheduler()
+
+	// Sweep abandoned resumable uploads
+	go media.RunUploadJanitor()
+
+	// Delete media nothing refers to any more
+	go media.RunMediaGC()
+
+	// Transcode uploaded videos to a mobile-friendly rendition
+	go media.RunTranscoder()
 
 	// Create the Gin router
 	r := server.NewRouter()
@@ -2,12 +2,14 @@ package server
 
 import (
 	"gochat_server/internal/api/auth"
+	"gochat_server/internal/api/broadcast"
+	"gochat_server/internal/api/channel"
 	"gochat_server/internal/api/chat"
+	"gochat_server/internal/api/community"
 	"gochat_server/internal/api/contacts"
 	"gochat_server/internal/api/fcm"
 	"gochat_server/internal/api/group"
 	"gochat_server/internal/api/media"
-	"gochat_server/internal/api/file"
 	"gochat_server/internal/api/websocket"
 
 	"github.com/gin-gonic/gin"
@@ -32,21 +34,91 @@ func NewRouter() *gin.Engine {
 		api.POST("/fcm/unset-fcm-token", fcm.UnsetFCMToken)
 
 		api.GET("/userdata", auth.GetUserDataHandler)
+		api.PUT("/profile-picture", auth.SetProfilePicture)
 
 		api.GET("/chats", chat.GetChatsHandler)
+		api.GET("/chats/:id/pins", websocket.GetPinnedMessages)
+		api.GET("/chats/:id/mentions", websocket.GetChatMentions)
 
+		api.GET("/starred", websocket.GetStarredMessages)
+		api.POST("/starred", websocket.StarMessage)
+		api.DELETE("/starred/:message_id", websocket.UnstarMessage)
+
+		api.GET("/messages/:id/history", websocket.GetMessageHistory)
+
+		api.GET("/scheduled-messages", websocket.GetScheduledMessages)
+		api.PUT("/scheduled-messages/:id", websocket.UpdateScheduledMessage)
+		api.DELETE("/scheduled-messages/:id", websocket.CancelScheduledMessage)
+
+		api.POST("/broadcast-lists", broadcast.CreateList)
+		api.GET("/broadcast-lists", broadcast.GetLists)
+		api.GET("/broadcast-lists/:id", broadcast.GetList)
+		api.PUT("/broadcast-lists/:id", broadcast.UpdateList)
+		api.DELETE("/broadcast-lists/:id", broadcast.DeleteList)
+		api.GET("/broadcast-lists/:id/messages", broadcast.GetListMessages)
+		api.GET("/broadcast-messages/:id", broadcast.GetMessageStatus)
+
+		api.POST("/channels", channel.CreateChannel)
+		api.GET("/channels", channel.GetFollowedChannels)
+		api.GET("/channels/:id", channel.GetChannel)
+		api.PUT("/channels/:id", channel.UpdateChannel)
+		api.POST("/channels/:id/follow", channel.FollowChannel)
+		api.DELETE("/channels/:id/follow", channel.UnfollowChannel)
+		api.POST("/channels/:id/admins/:member_id", channel.AddChannelAdmin)
+		api.DELETE("/channels/:id/admins/:member_id", channel.RemoveChannelAdmin)
+		api.GET("/channels/:id/feed", channel.GetFeed)
+		api.POST("/channels/:id/posts/:post_id/views", channel.ViewPost)
+
+		api.GET("/groups", group.GetMyGroups)
 		api.POST("/groups/create-group", group.CreateGroup)
-		api.DELETE("/groups/delete-group", group.DeleteGroup)
+		api.DELETE("/groups/delete-group/:id", group.DeleteGroup)
 		api.POST("/groups/join-group/:id", group.JoinGroup)
-		api.DELETE("/groups/leave-group", group.LeaveGroup)
-		api.PUT("/groups/update-group", group.UpdateGroup)
+		api.DELETE("/groups/leave-group/:id", group.LeaveGroup)
+		api.PUT("/groups/update-group/:id", group.UpdateGroup)
 		api.GET("/groups/get-group-data/:id", group.GetGroupData)
+		api.PUT("/groups/mute-group/:id", group.MuteGroup)
+		api.GET("/groups/:id/audit-log", group.GetAuditLog)
+		api.GET("/groups/:id/join-requests", group.GetJoinRequests)
+		api.POST("/groups/join-requests/:id/approve", group.ApproveJoinRequest)
+		api.POST("/groups/join-requests/:id/reject", group.RejectJoinRequest)
+		api.DELETE("/groups/join-requests/:id", group.CancelJoinRequest)
+		api.GET("/groups/:id/invite", group.GetInvite)
+		api.POST("/groups/:id/invite", group.RotateInvite)
+		api.DELETE("/groups/:id/invite", group.RevokeInvite)
+		api.POST("/groups/:id/admins/:member_id", group.PromoteMember)
+		api.DELETE("/groups/:id/admins/:member_id", group.DemoteMember)
+		api.GET("/groups/:id/members", group.GetGroupMembers)
+		api.DELETE("/groups/:id/members/:member_id", group.RemoveMember)
+		api.POST("/groups/:id/transfer-ownership/:member_id", group.TransferOwnership)
+		api.GET("/groups/invite/:code", group.PreviewInvite)
+		api.POST("/groups/invite/:code/join", group.JoinByInvite)
+
+		api.POST("/communities", community.CreateCommunity)
+		api.GET("/communities/:id", community.GetCommunity)
+		api.GET("/communities/:id/members", community.GetCommunityMembers)
+		api.POST("/communities/:id/groups/:group_id", community.AddCommunityGroup)
+		api.DELETE("/communities/:id/groups/:group_id", community.RemoveCommunityGroup)
+		api.POST("/communities/:id/leave", community.LeaveCommunity)
 
+		api.POST("/media/upload", media.Upload)
+		api.GET("/media/hash/:sha256", media.HasHash)
+		api.POST("/media/from-hash", media.CreateFromHash)
+		api.POST("/media/uploads", media.CreateUpload)
+		api.HEAD("/media/uploads/:id", media.GetUploadOffset)
+		api.PATCH("/media/uploads/:id", media.UploadChunk)
+		api.POST("/media/uploads/:id/finalize", media.FinalizeUpload)
+		api.DELETE("/media/uploads/:id", media.CancelUpload)
+		api.GET("/media/:id", media.Download)
+		api.GET("/media/:id/info", media.GetMediaInfo)
+		api.GET("/media/:id/signed-url", media.GetSignedURL)
+		api.GET("/me/storage", media.GetStorageUsage)
+
+		// Routes of the old image and file upload stacks
 		api.POST("/media/upload-image", media.UploadImage)
 		api.GET("/media/image/:id", media.ServeImage)
-
-		api.POST("/file/upload", file.UploadFile)
-		api.GET("file/download/:file_id", file.DownloadFile)
+		api.POST("/file/upload", media.UploadFile)
+		api.GET("file/download/:file_id", media.DownloadFile)
+		api.GET("/file/thumbnail/:file_id", media.ServeThumbnail)
 	}
 
 	return r